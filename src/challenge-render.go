@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+// Challenge fields as they are sent to CTFd, after mappings and adopted overrides are applied
+type RenderedChallenge struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Points      int    `json:"points"`
+	Decay       int    `json:"decay"`
+	MinPoints   int    `json:"min_points"`
+	State       string `json:"state"`
+	Connection  string `json:"connection"`
+	Type        string `json:"type"`     // CTFd challenge type, selected by the scoring model
+	Function    string `json:"function"` // Decay function of dynamic challenges
+	Attribution string `json:"attribution"`
+	MaxAttempts int    `json:"max_attempts"` // 0 allows unlimited attempts
+}
+
+// Render the challenge config into the values sent to CTFd.
+// files are the files of the challenge in CTFd, used to link files from the description and connection templates. Nil if not uploaded yet.
+func renderCTFdChallenge(challenge *ChallengeConfig, mappingMap MappingMap, files []*ctfd.File) (*RenderedChallenge, error) {
+	state := "visible"
+	if !challenge.Challenge.Enabled {
+		state = "hidden"
+	}
+
+	challType, function, err := getChallengeScoring(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	points, decay, minimum := getChallengePoints(challenge, mappingMap)
+
+	maxAttempts := mappingMap.MaxAttempts
+	if challenge.Challenge.MaxAttempts != nil {
+		maxAttempts = *challenge.Challenge.MaxAttempts
+	}
+	if maxAttempts < 0 {
+		return nil, fmt.Errorf("challenge %s has a negative max_attempts", challenge.Challenge.Slug)
+	}
+
+	removeHeader := true
+	if challenge.Challenge.RemoveDescriptionHeader != nil {
+		removeHeader = *challenge.Challenge.RemoveDescriptionHeader
+	}
+	markdown, err := getChallengeDescription(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("challenge %s: %w", challenge.Challenge.Slug, err)
+	}
+	description := renderDescription(markdown, removeHeader)
+
+	body := description.Body
+	connection := challenge.Challenge.Connection
+	if isTemplatingEnabled(challenge) {
+		vars, err := getTemplateVars(getNamespace())
+		if err != nil {
+			return nil, fmt.Errorf("error getting template variables: %w", err)
+		}
+		data := TemplateData{
+			Vars:        vars,
+			Slug:        challenge.Challenge.Slug,
+			Name:        challenge.Challenge.Name,
+			Author:      challenge.Challenge.Author,
+			Category:    getCategoryName(challenge, mappingMap),
+			Difficulty:  getDifficultyName(challenge, mappingMap),
+			Points:      points,
+			FrontMatter: description.FrontMatter,
+		}
+
+		body, err = renderChallengeTemplate("description", body, data, files)
+		if err != nil {
+			return nil, fmt.Errorf("challenge %s: %w", challenge.Challenge.Slug, err)
+		}
+		connection, err = renderChallengeTemplate("connection", connection, data, files)
+		if err != nil {
+			return nil, fmt.Errorf("challenge %s: %w", challenge.Challenge.Slug, err)
+		}
+	}
+
+	rendered := &RenderedChallenge{
+		Name:        challenge.Challenge.Name,
+		Category:    getCategoryName(challenge, mappingMap),
+		Description: publishInDescription(body, challenge, mappingMap),
+		Points:      points,
+		Decay:       decay,
+		MinPoints:   minimum,
+		State:       state,
+		Connection:  connection,
+		Type:        challType,
+		Function:    function,
+		Attribution: getChallengeAttribution(challenge, mappingMap),
+		MaxAttempts: maxAttempts,
+	}
+
+	// Apply the values adopted from CTFd on top of the challenge config
+	overrides, err := getChallengeOverridesData(challenge.Challenge.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("error getting overrides for challenge %s: %w", challenge.Challenge.Slug, err)
+	}
+	if overrides != "" {
+		if err := json.Unmarshal([]byte(overrides), rendered); err != nil {
+			return nil, fmt.Errorf("error parsing overrides for challenge %s: %w", challenge.Challenge.Slug, err)
+		}
+	}
+
+	return rendered, nil
+}
+
+// Get the CTFd challenge type and decay function of the scoring model of the challenge
+func getChallengeScoring(challenge *ChallengeConfig) (string, string, error) {
+	scoring := challenge.Challenge.Scoring
+	if scoring == "" {
+		scoring = "dynamic"
+	}
+
+	switch scoring {
+	case "static":
+		if challenge.Challenge.Type == "instanced" {
+			return "", "", fmt.Errorf("challenge %s is instanced, which only supports dynamic scoring", challenge.Challenge.Slug)
+		}
+		if challenge.Challenge.DecayFunction != "" {
+			return "", "", fmt.Errorf("challenge %s has a decay function, which requires dynamic scoring", challenge.Challenge.Slug)
+		}
+		return "standard", "", nil
+	case "dynamic":
+		function := challenge.Challenge.DecayFunction
+		if function == "" {
+			function = "logarithmic"
+		}
+		if function != "linear" && function != "logarithmic" {
+			return "", "", fmt.Errorf("invalid decay function %q for challenge %s, valid values are: linear, logarithmic", function, challenge.Challenge.Slug)
+		}
+
+		challType := "dynamic"
+		if challenge.Challenge.Type == "instanced" {
+			challType = "kubectf"
+		}
+		return challType, function, nil
+	default:
+		return "", "", fmt.Errorf("invalid scoring %q for challenge %s, valid values are: static, dynamic", scoring, challenge.Challenge.Slug)
+	}
+}
+
+// Get the point parameters of the rendered challenge for CTFd, according to the scoring model.
+// live is the challenge in CTFd, or nil if it is being created.
+// Older CTFd versions do not support decay functions, so the default logarithmic function is only sent if the live challenge has a function.
+func (rendered *RenderedChallenge) scoringParams(live *ctfd.Challenge) (value *int, initial *int, decay *int, minimum *int, function *string) {
+	if rendered.Type == "standard" {
+		return &rendered.Points, nil, nil, nil, nil
+	}
+	if rendered.Function != "logarithmic" || (live != nil && live.Function != nil) {
+		function = &rendered.Function
+	}
+	return nil, &rendered.Points, &rendered.Decay, &rendered.MinPoints, function
+}
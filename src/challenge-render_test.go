@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+func TestScoringParamsFunction(t *testing.T) {
+	logarithmic := "logarithmic"
+
+	tests := []struct {
+		name     string
+		rendered RenderedChallenge
+		live     *ctfd.Challenge
+		function string // Empty if the function is not sent
+	}{
+		{
+			name:     "static",
+			rendered: RenderedChallenge{Type: "standard"},
+		},
+		{
+			name:     "create with the default function",
+			rendered: RenderedChallenge{Type: "dynamic", Function: "logarithmic"},
+		},
+		{
+			name:     "create with a linear function",
+			rendered: RenderedChallenge{Type: "dynamic", Function: "linear"},
+			function: "linear",
+		},
+		{
+			name:     "update without a live function",
+			rendered: RenderedChallenge{Type: "dynamic", Function: "logarithmic"},
+			live:     &ctfd.Challenge{},
+		},
+		{
+			name:     "update with a live function",
+			rendered: RenderedChallenge{Type: "dynamic", Function: "logarithmic"},
+			live:     &ctfd.Challenge{Function: &logarithmic},
+			function: "logarithmic",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, _, _, function := test.rendered.scoringParams(test.live)
+			if got := stringPointerValue(function); got != test.function {
+				t.Errorf("function = %q, want %q", got, test.function)
+			}
+		})
+	}
+}
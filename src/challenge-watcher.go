@@ -1,16 +1,17 @@
 package main
 
 import (
-	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
 )
 
 var watchedConfigMaps = "challenges.ctfpilot.com/configmap"
@@ -23,125 +24,221 @@ func initBackgroundChallengeWatcher() error {
 		return errors.New("Kubernetes client not initialized")
 	}
 
-	// Initialize the watcher for challenges
-	for {
-		watcher, err := clientset.CoreV1().ConfigMaps(getNamespace()).Watch(context.TODO(), metav1.ListOptions{
-			LabelSelector: watchedConfigMaps,
-		})
+	// The informer lists and watches the labelled configmaps, resuming from the last seen
+	// resourceVersion (using bookmarks) and relisting with exponential backoff on failures.
+	// Watch timeouts from the API server are therefore handled without restarting the service.
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		clientset,
+		getWatcherResyncInterval(),
+		informers.WithNamespace(getNamespace()),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = watchedConfigMaps
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	err := informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		log.Printf("Challenge watcher lost connection, resuming with backoff: %v\n", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			enqueueConfigMap(obj)
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldMap, oldOk := oldObj.(*corev1.ConfigMap)
+			newMap, newOk := newObj.(*corev1.ConfigMap)
+			if oldOk && newOk && onlyMetadataChanged(oldMap, newMap) {
+				// Skip changes to annotations, such as the sync status written by the manager
+				return
+			}
+			enqueueConfigMap(newObj)
+		},
+		DeleteFunc: func(obj any) {
+			// The object may be a tombstone, if the deletion was missed while disconnected
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if configMap, ok := obj.(*corev1.ConfigMap); ok {
+				key, err := cache.MetaNamespaceKeyFunc(configMap)
+				if err != nil {
+					log.Printf("Error getting key for deleted configmap %s: %v\n", configMap.Name, err)
+					return
+				}
+				// Keep the last known state, as it is needed to find the CTFd object to remove
+				deletedConfigMaps.Store(key, configMap)
+				enqueueSyncKey(key)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	factory.Start(stop)
+
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		return errors.New("unable to sync challenge watcher cache")
+	}
+	log.Println("Background challenge watcher synced")
+
+	// Process the queued configmaps until the process exits
+	runSyncWorker(informer.GetIndexer())
+	return nil
+}
+
+func enqueueConfigMap(obj any) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Printf("Error getting key for configmap: %v\n", err)
+		return
+	}
+	deletedConfigMaps.Delete(key)
+	enqueueSyncKey(key)
+}
+
+// Sync the configmap behind the key, using the informer cache or the last known state if deleted
+func syncConfigMapKey(indexer cache.Indexer, key string) error {
+	obj, exists, err := indexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		configMap, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return fmt.Errorf("unexpected object type %T for %s", obj, key)
+		}
+		return processConfigMapUpdate(configMap)
+	}
+
+	deleted, ok := deletedConfigMaps.Load(key)
+	if !ok {
+		log.Printf("Configmap %s no longer exists, nothing to sync\n", key)
+		return nil
+	}
+	err = processConfigMapDelete(deleted.(*corev1.ConfigMap))
+	if err == nil {
+		deletedConfigMaps.Delete(key)
+	}
+	return err
+}
+
+func processConfigMapUpdate(updatedMap *corev1.ConfigMap) error {
+	if hasBeenDeployed(updatedMap) {
+		log.Printf("Challenge configmap %s has not changed since last deployment, skipping update\n", updatedMap.Name)
+		return nil
+	}
+
+	// Generate the hash before syncing, so the annotated hash matches the deployed data
+	newHash, err := getHashForConfigMap(updatedMap)
+	if err != nil {
+		log.Printf("Error generating hash for configmap %s: %v\n", updatedMap.Name, err)
+		return err
+	}
+
+	id, err := syncConfigMap(updatedMap)
+	if id == 0 && err == nil {
+		// Nothing was synced
+		return nil
+	}
+	recordConfigMapSync(updatedMap, id, newHash, err)
+	if err != nil {
+		return err
+	}
+
+	// Store the hash of the configmap to avoid re-deploying unchanged challenges that have already been successfully deployed
+	err = storeConfigmapHash(updatedMap.Name, newHash)
+	if err != nil {
+		log.Printf("Error storing hash for configmap %s: %v\n", updatedMap.Name, err)
+		return err
+	}
+
+	return nil
+}
+
+// Upload or update the challenge or page of the configmap in CTFd, returning the CTFd ID
+func syncConfigMap(updatedMap *corev1.ConfigMap) (int, error) {
+	log.Printf("Challenge configmap added or updated: %s\n", updatedMap.Name)
+	configMapType := getConfigMapType(updatedMap)
+	if configMapType == "challenge" {
+		log.Printf("Challenge configmap added or updated: %s\n", updatedMap.Name)
+
+		challengeConfigMap, err := extractChallengeConfigMap(updatedMap)
+		if err != nil {
+			log.Printf("Error extracting challenge configmap: %v\n", err)
+			return 0, err
+		}
+
+		id, err := updateOrCreateCTFdChallenge(challengeConfigMap)
 		if err != nil {
-			panic("Unable to create watcher")
+			log.Printf("Error updating or creating challenge in CTFd: %v\n", err)
+			return 0, err
 		}
-		processEvent(watcher.ResultChan())
+		log.Printf("Challenge updated or created with ID: %d\n", id)
+		return id, nil
+	} else if configMapType == "page" {
+		log.Printf("Page configmap added or updated: %s\n", updatedMap.Name)
+
+		pageConfigMap, err := extractPageConfigMap(updatedMap)
+		if err != nil {
+			log.Printf("Error extracting page configmap: %v\n", err)
+			return 0, err
+		}
+		id, err := uploadOrUpdateCTFdPage(&pageConfigMap.Page)
+		if err != nil {
+			log.Printf("Error updating or creating page in CTFd: %v\n", err)
+			return 0, err
+		}
+		log.Printf("Page updated or created with ID: %d\n", id)
+		return id, nil
 	}
+
+	log.Printf("Unknown configmap type for %s, skipping\n", updatedMap.Name)
+	return 0, nil
 }
 
-func processEvent(eventChannel <-chan watch.Event) {
-	for {
-		event, open := <-eventChannel
-		if open {
-			switch event.Type {
-			case watch.Added, watch.Modified:
-				if updatedMap, ok := event.Object.(*corev1.ConfigMap); ok {
-					if !hasBeenDeployed(updatedMap) {
-						log.Printf("Challenge configmap added or updated: %s\n", updatedMap.Name)
-						configMapType := getConfigMapType(updatedMap)
-						if configMapType == "challenge" {
-							log.Printf("Challenge configmap added or updated: %s\n", updatedMap.Name)
-
-							challengeConfigMap, err := extractChallengeConfigMap(updatedMap)
-							if err != nil {
-								log.Printf("Error extracting challenge configmap: %v\n", err)
-								continue
-							}
-
-							id, err := updateOrCreateCTFdChallenge(challengeConfigMap)
-							if err != nil {
-								log.Printf("Error updating or creating challenge in CTFd: %v\n", err)
-								continue
-							} else {
-								log.Printf("Challenge updated or created with ID: %d\n", id)
-							}
-						} else if configMapType == "page" {
-							log.Printf("Page configmap added or updated: %s\n", updatedMap.Name)
-
-							pageConfigMap, err := extractPageConfigMap(updatedMap)
-							if err != nil {
-								log.Printf("Error extracting page configmap: %v\n", err)
-								continue
-							}
-							id, err := uploadOrUpdateCTFdPage(&pageConfigMap.Page)
-							if err != nil {
-								log.Printf("Error updating or creating page in CTFd: %v\n", err)
-								continue
-							} else {
-								log.Printf("Page updated or created with ID: %d\n", id)
-							}
-						} else {
-							log.Printf("Unknown configmap type for %s, skipping\n", updatedMap.Name)
-							continue
-						}
-
-						// Store the hash of the configmap to avoid re-deploying unchanged challenges that have already been successfully deployed
-						newHash, err := getHashForConfigMap(updatedMap)
-						if err != nil {
-							log.Printf("Error generating hash for configmap %s: %v\n", updatedMap.Name, err)
-							continue
-						}
-						err = storeConfigmapHash(getNamespace(), updatedMap.Name, newHash)
-						if err != nil {
-							log.Printf("Error storing hash for configmap %s: %v\n", updatedMap.Name, err)
-							continue
-						}
-					} else {
-						log.Printf("Challenge configmap %s has not changed since last deployment, skipping update\n", updatedMap.Name)
-					}
-				}
-			case watch.Deleted:
-				if deletedMap, ok := event.Object.(*corev1.ConfigMap); ok {
-					log.Printf("Challenge configmap deleted: %s\n", deletedMap.Name)
-
-					configMapType := getConfigMapType(deletedMap)
-					if configMapType == "challenge" {
-						challengeConfigMap, err := extractChallengeConfigMap(deletedMap)
-						if err != nil {
-							log.Printf("Error extracting challenge configmap: %v\n", err)
-							continue
-						}
-
-						err = disableCTFdChallenge(challengeConfigMap)
-						if err != nil {
-							log.Printf("Error disabling challenge in CTFd: %v\n", err)
-						}
-
-						storeConfigmapHash(getNamespace(), deletedMap.Name, "") // Clear the stored hash for this configmap
-					} else if configMapType == "page" {
-						pageConfigMap, err := extractPageConfigMap(deletedMap)
-						if err != nil {
-							log.Printf("Error extracting page configmap: %v\n", err)
-							continue
-						}
-
-						err = deleteCTFdPage(pageConfigMap.Slug)
-						if err != nil {
-							log.Printf("Error deleting page in CTFd: %v\n", err)
-						}
-
-						storeConfigmapHash(getNamespace(), deletedMap.Name, "") // Clear the stored hash for this configmap
-					} else {
-						log.Printf("Unknown configmap type for %s, skipping deletion\n", deletedMap.Name)
-					}
-				}
-			default:
-				// Do nothing
-				log.Printf("Unhandled event type: %s for configmap: %s\n", event.Type, event.Object.(*corev1.ConfigMap).Name)
-			}
-		} else {
-			// If eventChannel is closed, it means the server has closed the connection
-			setUnhealthy()
-			log.Println("Event channel closed, stopping watcher")
-			return
+func processConfigMapDelete(deletedMap *corev1.ConfigMap) error {
+	log.Printf("Challenge configmap deleted: %s\n", deletedMap.Name)
+
+	configMapType := getConfigMapType(deletedMap)
+	if configMapType == "challenge" {
+		challengeConfigMap, err := extractChallengeConfigMap(deletedMap)
+		if err != nil {
+			log.Printf("Error extracting challenge configmap: %v\n", err)
+			return err
+		}
+
+		err = disableCTFdChallenge(challengeConfigMap.Challenge.Slug)
+		if err != nil {
+			log.Printf("Error disabling challenge in CTFd: %v\n", err)
+			return err
+		}
+
+		storeConfigmapHash(deletedMap.Name, "") // Clear the stored hash for this configmap
+	} else if configMapType == "page" {
+		pageConfigMap, err := extractPageConfigMap(deletedMap)
+		if err != nil {
+			log.Printf("Error extracting page configmap: %v\n", err)
+			return err
+		}
+
+		err = deleteCTFdPage(pageConfigMap.Slug)
+		if err != nil {
+			log.Printf("Error deleting page in CTFd: %v\n", err)
+			return err
 		}
+
+		storeConfigmapHash(deletedMap.Name, "") // Clear the stored hash for this configmap
+	} else {
+		log.Printf("Unknown configmap type for %s, skipping deletion\n", deletedMap.Name)
 	}
+
+	return nil
 }
 
 func getConfigMapType(configMap *corev1.ConfigMap) string {
@@ -169,7 +266,7 @@ func hasBeenDeployed(configMap *corev1.ConfigMap) bool {
 		return false
 	}
 
-	oldHash, err := getConfigmapStoredHash(getNamespace(), configMap.Name)
+	oldHash, err := getConfigmapStoredHash(configMap.Name)
 	if err != nil {
 		log.Printf("Error getting stored hash for configmap %s: %v\n", configMap.Name, err)
 		return false
@@ -177,43 +274,25 @@ func hasBeenDeployed(configMap *corev1.ConfigMap) bool {
 	return newHash == oldHash
 }
 
-// getConfigmapHashSet fetches the 'challenge-configmap-hashset' ConfigMap and returns a map of configmap names to their stored hashes.
-func getConfigmapHashSet(namespace string) (map[string]string, error) {
-	cm, err := getConfigMap(namespace, "challenge-configmap-hashset")
-	if err != nil {
-		return nil, err
-	}
-	// Assume the Data field is map[string]string where key is configmap name, value is hash
-	return cm.Data, nil
+// getConfigmapHashSet returns a map of configmap names to their stored hashes.
+func getConfigmapHashSet() (map[string]string, error) {
+	return hashStore.List()
 }
 
-// getConfigmapStoredHash returns the stored hash for a configmap from the hashset configmap
-func getConfigmapStoredHash(namespace, configmapName string) (string, error) {
-	hashSet, err := getConfigmapHashSet(namespace)
+// getConfigmapStoredHash returns the stored hash for a configmap from the hashset
+func getConfigmapStoredHash(configmapName string) (string, error) {
+	hash, ok, err := hashStore.Get(configmapName)
 	if err != nil {
 		return "", err
 	}
-	hash, ok := hashSet[configmapName]
 	if !ok {
 		return "", nil // Not found
 	}
 	return hash, nil
 }
 
-func storeConfigmapHash(namespace, configmapName, hash string) error {
-	// Use getConfigMap to fetch, and updateConfigMap to update
-	configMap, err := getConfigmapHashSet(namespace)
-	if err != nil {
-		return err
-	}
-
-	data := configMap
-	if data == nil {
-		data = make(map[string]string)
-	}
-	data[configmapName] = hash
-
-	err = updateConfigMap(namespace, "challenge-configmap-hashset", data)
+func storeConfigmapHash(configmapName, hash string) error {
+	err := hashStore.Set(configmapName, hash)
 	if err != nil {
 		return err
 	}
@@ -229,6 +308,22 @@ func getHashForConfigMap(configMap *corev1.ConfigMap) (string, error) {
 		return "", err
 	}
 
+	// Include the description file, so changes to it are deployed even if the configmap is unchanged
+	if getConfigMapType(configMap) == "challenge" {
+		challengeConfig, err := extractChallengeConfigMap(configMap)
+		if err == nil && challengeConfig.Challenge.DescriptionLocation != "" {
+			source, err := challengeFileSource(challengeConfig)
+			if err != nil {
+				return "", err
+			}
+			sha, err := source.FileVersion(challengeRef(challengeConfig), descriptionFilePath(challengeConfig))
+			if err != nil {
+				return "", fmt.Errorf("error getting description file of configmap %s: %w", configMap.Name, err)
+			}
+			data = append(data, []byte(sha)...)
+		}
+	}
+
 	// Generate a hash from the JSON string
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:]), nil
@@ -2,32 +2,38 @@ package main
 
 import (
 	"encoding/json"
+	"strings"
 )
 
 type Challenge struct {
-	Schema              string   `json:"$schema"`
-	Enabled             bool     `json:"enabled"`
-	Name                string   `json:"name"`
-	Slug                string   `json:"slug"`
-	Author              string   `json:"author,omitempty"`
-	Prerequisites       []string `json:"prerequisites,omitempty"`
-	Category            string   `json:"category"`
-	Difficulty          string   `json:"difficulty"`
-	Tags                []string `json:"tags,omitempty"`
-	Type                string   `json:"type"`
-	InstancedType       string   `json:"instanced_type,omitempty"`
-	InstancedName       string   `json:"instanced_name,omitempty"`
-	InstancedSubdomains []string `json:"instanced_subdomains,omitempty"`
-	Connection          string   `json:"connection,omitempty"` // Maximum length 255 characters
-	Flag                []struct {
-		Flag          string `json:"flag"`
-		CaseSensitive bool   `json:"case_sensitive"`
-	} `json:"flag,omitempty"`
-	Points              int    `json:"points"`
-	Decay               int    `json:"decay,omitempty"` // Maximum length 255 characters
-	MinPoints           int    `json:"min_points"`
-	DescriptionLocation string `json:"description_location,omitempty"`
-	DockerfileLocations []struct {
+	Schema                  string          `json:"$schema"`
+	Enabled                 bool            `json:"enabled"`
+	Name                    string          `json:"name"`
+	Slug                    string          `json:"slug"`
+	Author                  string          `json:"author,omitempty"`
+	Prerequisites           []string        `json:"prerequisites,omitempty"`
+	AnonymizePrerequisites  bool            `json:"anonymize_prerequisites,omitempty"` // Show the challenge anonymized until the prerequisites are solved, instead of hiding it
+	Category                string          `json:"category"`
+	Difficulty              string          `json:"difficulty"`
+	Tags                    []string        `json:"tags,omitempty"`
+	Type                    string          `json:"type"`
+	InstancedType           string          `json:"instanced_type,omitempty"`
+	InstancedName           string          `json:"instanced_name,omitempty"`
+	InstancedSubdomains     []string        `json:"instanced_subdomains,omitempty"`
+	Connection              string          `json:"connection,omitempty"` // Maximum length 255 characters
+	Flag                    []ChallengeFlag `json:"flag,omitempty"`
+	Hints                   []ChallengeHint `json:"hints,omitempty"`
+	Scoring                 string          `json:"scoring,omitempty"`          // "static" or "dynamic" (default)
+	DecayFunction           string          `json:"decay_function,omitempty"`   // "linear" or "logarithmic" (default), for dynamic scoring
+	ScoringOverride         bool            `json:"scoring_override,omitempty"` // Ignore the difficulty scoring, using the points, decay and min_points of the challenge as is
+	MaxAttempts             *int            `json:"max_attempts,omitempty"`     // 0 allows unlimited attempts. Defaults to max-attempts of the mapping map
+	Points                  int             `json:"points"`
+	Decay                   int             `json:"decay,omitempty"` // Maximum length 255 characters
+	MinPoints               int             `json:"min_points"`
+	DescriptionLocation     string          `json:"description_location,omitempty"`
+	Templating              *bool           `json:"templating,omitempty"`                // Render the description and connection as Go templates. Defaults to false
+	RemoveDescriptionHeader *bool           `json:"remove_description_header,omitempty"` // Remove the leading level 1 heading of the description. Defaults to true
+	DockerfileLocations     []struct {
 		Context    string `json:"context"`
 		Location   string `json:"location"`
 		Identifier any    `json:"identifier"`
@@ -38,6 +44,8 @@ type ChallengeConfig struct {
 	Name        string    `json:"name"`
 	Path        string    `json:"path"`
 	Repository  string    `json:"repository"`
+	Ref         string    `json:"ref,omitempty"`    // Branch, tag or commit SHA of the repository. Defaults to GITHUB_BRANCH
+	Commit      string    `json:"commit,omitempty"` // Commit SHA the config was generated from. Files are fetched at this commit, instead of the ref
 	Challenge   Challenge `json:"challenge"`
 	Description string    `json:"description"`
 	GeneratedAt string    `json:"generated_at"`
@@ -69,6 +77,29 @@ func filesDirPath(challengeConfig *ChallengeConfig) string {
 	return challengeConfig.Path + "/" + filesDir
 }
 
+// Get the repository of the challenge, defaulting to GITHUB_REPO. See newFileSource for the supported repositories.
+func challengeRepository(challengeConfig *ChallengeConfig) string {
+	repository := strings.TrimSpace(challengeConfig.Repository)
+	if repository == "" {
+		return getGithubRepo()
+	}
+	return repository
+}
+
+// Get the ref of the repository of the challenge.
+// Uses the pinned commit if set, otherwise the ref of the challenge, defaulting to GITHUB_BRANCH.
+func challengeRef(challengeConfig *ChallengeConfig) string {
+	if challengeConfig.Commit != "" {
+		return challengeConfig.Commit
+	}
+
+	ref := strings.TrimSpace(challengeConfig.Ref)
+	if ref == "" {
+		return getGithubBranch()
+	}
+	return ref
+}
+
 func getCategoryName(challengeConfig *ChallengeConfig, mappingMap MappingMap) string {
 	// Get the category from the challenge config
 	category := challengeConfig.Challenge.Category
@@ -108,3 +139,29 @@ func getDifficultyName(challengeConfig *ChallengeConfig, mappingMap MappingMap)
 	// Default to the original difficulty if not found in mapping
 	return difficulty
 }
+
+// Get the points, decay and minimum points of the challenge.
+// The difficulty scoring of the mapping map fills the values the challenge omits, unless scoring_override is set.
+// Values set by the challenge are never replaced.
+func getChallengePoints(challengeConfig *ChallengeConfig, mappingMap MappingMap) (points int, decay int, minimum int) {
+	points = challengeConfig.Challenge.Points
+	decay = challengeConfig.Challenge.Decay
+	minimum = challengeConfig.Challenge.MinPoints
+
+	scoring, exists := mappingMap.DifficultyScoring[challengeConfig.Challenge.Difficulty]
+	if !exists || challengeConfig.Challenge.ScoringOverride {
+		return points, decay, minimum
+	}
+
+	if points == 0 {
+		points = scoring.Points
+	}
+	if decay == 0 {
+		decay = scoring.Decay
+	}
+	if minimum == 0 {
+		minimum = scoring.Minimum
+	}
+
+	return points, decay, minimum
+}
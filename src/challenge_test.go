@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestGetChallengePoints(t *testing.T) {
+	mappingMap := MappingMap{
+		DifficultyScoring: map[string]DifficultyScoring{
+			"easy": {Points: 100, Decay: 10, Minimum: 50},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		challenge Challenge
+		points    int
+		decay     int
+		minimum   int
+	}{
+		{
+			name:      "no difficulty scoring",
+			challenge: Challenge{Difficulty: "hard", Points: 300},
+			points:    300,
+		},
+		{
+			name:      "all values omitted",
+			challenge: Challenge{Difficulty: "easy"},
+			points:    100,
+			decay:     10,
+			minimum:   50,
+		},
+		{
+			name:      "some values set",
+			challenge: Challenge{Difficulty: "easy", Points: 150, MinPoints: 75},
+			points:    150,
+			decay:     10,
+			minimum:   75,
+		},
+		{
+			name:      "all values set",
+			challenge: Challenge{Difficulty: "easy", Points: 200, Decay: 5, MinPoints: 20},
+			points:    200,
+			decay:     5,
+			minimum:   20,
+		},
+		{
+			name:      "scoring override",
+			challenge: Challenge{Difficulty: "easy", Points: 150, ScoringOverride: true},
+			points:    150,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			points, decay, minimum := getChallengePoints(&ChallengeConfig{Challenge: test.challenge}, mappingMap)
+			if points != test.points || decay != test.decay || minimum != test.minimum {
+				t.Errorf("got (%d, %d, %d), want (%d, %d, %d)", points, decay, minimum, test.points, test.decay, test.minimum)
+			}
+		})
+	}
+}
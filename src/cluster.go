@@ -5,17 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"regexp"
 	"slices"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
 var clientset *kubernetes.Clientset
 
+// Full git commit SHA
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+var clusterConfig *rest.Config
+
 func initClusterClient() error {
 	log.Println("Initializing Kubernetes client...")
 
@@ -40,6 +47,7 @@ func initClusterClient() error {
 
 	// Set the clientset
 	clientset = client
+	clusterConfig = config
 
 	log.Println("Kubernetes client initialized successfully")
 
@@ -91,6 +99,18 @@ func getConfigMapsByLabel(namespace string, labelSelector map[string]string) ([]
 	return configMapNames, nil
 }
 
+// List configmaps based on a label selector in the given namespace
+func listConfigMapsByLabel(namespace string, labelSelector map[string]string) ([]corev1.ConfigMap, error) {
+	configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.Set(labelSelector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return configMaps.Items, nil
+}
+
 // Get configmap by name in the given namespace and return the configmap as a dictionary
 func getConfigMap(namespace string, name string) (*corev1.ConfigMap, error) {
 	// Get the configmap in the given namespace
@@ -148,7 +168,6 @@ func extractChallengeConfigMap(configMap *corev1.ConfigMap) (*ChallengeConfig, e
 		"path",
 		"repository",
 		"challenge",
-		"description",
 	}
 
 	// Check if the configmap has the required elements
@@ -164,6 +183,8 @@ func extractChallengeConfigMap(configMap *corev1.ConfigMap) (*ChallengeConfig, e
 	challengeConfig.Name = configMap.Data["name"]
 	challengeConfig.Path = configMap.Data["path"]
 	challengeConfig.Repository = configMap.Data["repository"]
+	challengeConfig.Ref = configMap.Data["ref"]
+	challengeConfig.Commit = strings.ToLower(strings.TrimSpace(configMap.Data["commit"]))
 	challengeConfig.Description = configMap.Data["description"]
 	challengeConfig.GeneratedAt = configMap.Data["generated_at"]
 	challengeConfig.Challenge = Challenge{}
@@ -172,42 +193,61 @@ func extractChallengeConfigMap(configMap *corev1.ConfigMap) (*ChallengeConfig, e
 		return nil, err
 	}
 
+	if challengeConfig.Commit != "" && !commitPattern.MatchString(challengeConfig.Commit) {
+		return nil, errors.New("Configmap commit is not a full 40 character commit SHA: " + challengeConfig.Commit)
+	}
+
+	// The description is fetched from the repository instead, if description_location is set
+	if _, ok := configMap.Data["description"]; !ok && challengeConfig.Challenge.DescriptionLocation == "" {
+		return nil, errors.New("Configmap does not contain the required element: description")
+	}
+
 	return challengeConfig, nil
 }
 
-// Update configmap in the given namespace with the given name and data
+// Update configmap in the given namespace with the given name and data.
+// Retries on resourceVersion conflicts, in case the configmap was changed concurrently.
 func updateConfigMap(namespace string, name string, data map[string]string) error {
-	// Get the configmap in the given namespace
-	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the configmap in the given namespace
+		configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
 
-	if configMap == nil {
-		return errors.New("Configmap not found")
-	}
-	if configMap.Data == nil {
-		configMap.Data = make(map[string]string)
-	}
+		if configMap == nil {
+			return errors.New("Configmap not found")
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
 
-	// Update the configmap data
-	for key, value := range data {
-		configMap.Data[key] = value
-	}
+		// Update the configmap data
+		for key, value := range data {
+			configMap.Data[key] = value
+		}
 
-	// Update the configmap in the given namespace
-	_, err = clientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
-	if err != nil {
+		// Update the configmap in the given namespace
+		_, err = clientset.CoreV1().ConfigMaps(namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
 		return err
-	}
-
-	return nil
+	})
 }
 
 type MappingMap struct {
-	Categories        map[string]string `json:"categories"`
-	Difficulties      map[string]string `json:"difficulties"`
-	DifficultyMapping map[string]string `json:"difficulty-categories"`
+	Categories           map[string]string            `json:"categories"`
+	Difficulties         map[string]string            `json:"difficulties"`
+	DifficultyMapping    map[string]string            `json:"difficulty-categories"`
+	DifficultyScoring    map[string]DifficultyScoring `json:"difficulty-scoring"`
+	DifficultyPublishing string                       `json:"difficulty-publishing"` // Where the difficulty is published in CTFd
+	AuthorPublishing     string                       `json:"author-publishing"`     // Where the author is published in CTFd
+	MaxAttempts          int                          `json:"max-attempts"`          // Default max attempts of challenges, 0 for unlimited
+}
+
+// Default scoring of the challenges of a difficulty
+type DifficultyScoring struct {
+	Points  int `json:"points"`
+	Decay   int `json:"decay"`
+	Minimum int `json:"minimum"`
 }
 
 // Get map that maps categories, difficulty levels to their respective names. Also includes difficulty-category mapping.
@@ -228,6 +268,7 @@ func getMappingMap(namespace string) (MappingMap, error) {
 		Categories:        make(map[string]string),
 		Difficulties:      make(map[string]string),
 		DifficultyMapping: make(map[string]string),
+		DifficultyScoring: make(map[string]DifficultyScoring),
 	}
 
 	for key, value := range configMap.Data {
@@ -244,8 +285,27 @@ func getMappingMap(namespace string) (MappingMap, error) {
 			if err := json.Unmarshal([]byte(value), &mappingMap.DifficultyMapping); err != nil {
 				return MappingMap{}, err
 			}
+		case key == "difficulty-scoring":
+			if err := json.Unmarshal([]byte(value), &mappingMap.DifficultyScoring); err != nil {
+				return MappingMap{}, err
+			}
+		case key == "difficulty-publishing":
+			mappingMap.DifficultyPublishing = strings.TrimSpace(value)
+		case key == "author-publishing":
+			mappingMap.AuthorPublishing = strings.TrimSpace(value)
+		case key == "max-attempts":
+			if err := json.Unmarshal([]byte(value), &mappingMap.MaxAttempts); err != nil {
+				return MappingMap{}, err
+			}
+			if mappingMap.MaxAttempts < 0 {
+				return MappingMap{}, errors.New("max-attempts must not be negative")
+			}
 		}
 	}
 
+	if err := validatePublishing(mappingMap); err != nil {
+		return MappingMap{}, err
+	}
+
 	return mappingMap, nil
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	annotationCTFdID       = "challenges.ctfpilot.com/ctfd-id"
+	annotationSyncedHash   = "challenges.ctfpilot.com/synced-hash"
+	annotationSyncedAt     = "challenges.ctfpilot.com/synced-at"
+	annotationSyncError    = "challenges.ctfpilot.com/sync-error"
+	annotationSyncedCommit = "challenges.ctfpilot.com/synced-commit"
+)
+
+var eventRecorder record.EventRecorder
+
+func initEventRecorder() {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: clientset.CoreV1().Events(getNamespace()),
+	})
+	eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "ctfd-manager"})
+}
+
+// Write the outcome of a sync attempt back to the source configmap, as annotations and as an event
+func recordConfigMapSync(configMap *corev1.ConfigMap, id int, hash string, syncErr error) {
+	// A null value removes the annotation in a merge patch
+	annotations := map[string]*string{}
+	if syncErr == nil {
+		idValue := strconv.Itoa(id)
+		syncedAt := time.Now().UTC().Format(time.RFC3339)
+		annotations[annotationCTFdID] = &idValue
+		annotations[annotationSyncedHash] = &hash
+		annotations[annotationSyncedAt] = &syncedAt
+		annotations[annotationSyncError] = nil
+
+		// Record the commit the challenge files were fetched at, if pinned
+		annotations[annotationSyncedCommit] = nil
+		if commit := configMap.Data["commit"]; commit != "" && getConfigMapType(configMap) == "challenge" {
+			commit = strings.ToLower(strings.TrimSpace(commit))
+			annotations[annotationSyncedCommit] = &commit
+		}
+	} else {
+		errorValue := syncErr.Error()
+		annotations[annotationSyncError] = &errorValue
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		log.Printf("Error creating annotation patch for configmap %s: %v\n", configMap.Name, err)
+		return
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps(configMap.Namespace).Patch(context.TODO(), configMap.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		log.Printf("Error annotating configmap %s with sync status: %v\n", configMap.Name, err)
+	}
+
+	if eventRecorder == nil {
+		return
+	}
+	if syncErr == nil {
+		eventRecorder.Eventf(configMap, corev1.EventTypeNormal, "Synced", "Synced to CTFd with ID %d", id)
+	} else {
+		eventRecorder.Eventf(configMap, corev1.EventTypeWarning, "SyncFailed", "Failed to sync to CTFd: %v", syncErr)
+	}
+}
+
+// Check if only the metadata (e.g. the sync annotations) of a configmap changed
+func onlyMetadataChanged(oldMap *corev1.ConfigMap, newMap *corev1.ConfigMap) bool {
+	if oldMap.ResourceVersion == newMap.ResourceVersion {
+		// Periodic resync, not a change
+		return false
+	}
+
+	return maps.Equal(oldMap.Data, newMap.Data) &&
+		oldMap.Labels[watchedConfigMaps] == newMap.Labels[watchedConfigMaps]
+}
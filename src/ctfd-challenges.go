@@ -1,13 +1,21 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode"
 
 	ctfd "github.com/ctfer-io/go-ctfd/api"
+	"golang.org/x/text/unicode/norm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type KubeCTFPostChallengeParams struct {
@@ -28,6 +36,11 @@ type CTFdStateParams struct {
 	State string `json:"state"`
 }
 
+type CTFdChallengeTextParams struct {
+	Description    string `json:"description"`
+	ConnectionInfo string `json:"connection_info"`
+}
+
 func getCTFdChallenges() ([]*ctfd.Challenge, error) {
 	// Get client
 	client, err := getCTFdClient()
@@ -53,133 +66,218 @@ func getCTFdChallenges() ([]*ctfd.Challenge, error) {
 }
 
 func getUploadedCTFdChallenges() (map[string]string, error) {
-	// Get configmap for uploaded challenges
-	configMap, err := getConfigMap(getNamespace(), CTFDCHALLENGESCONFIGMAP)
+	// Get uploaded challenges from the state store
+	data, err := challengeStore.List()
 	if err != nil {
-		log.Println("Error getting CTFd challenges configmap:", err)
-		return nil, errors.New("error getting CTFd challenges configmap")
-	}
-
-	if configMap == nil {
-		log.Println("CTFd challenges configmap is nil")
-		return nil, errors.New("CTFd challenges configmap is nil")
+		log.Println("Error getting CTFd challenges state:", err)
+		return nil, errors.New("error getting CTFd challenges state")
 	}
 
-	if configMap.Data == nil {
-		log.Println("CTFd challenges configmap data is nil")
-		return make(map[string]string), nil
-	}
-
-	return configMap.Data, nil
+	return data, nil
 }
 
 func getUploadedCTFdChallenge(challengeName string) (string, error) {
-	// Get uploaded challenges
-	uploadedChallenges, err := getUploadedCTFdChallenges()
+	// Get uploaded challenge
+	uploadedChallenge, ok, err := challengeStore.Get(challengeName)
 	if err != nil {
 		log.Println("Error getting uploaded challenges:", err)
 		return "", errors.New("error getting uploaded challenges")
 	}
 
 	// Check if challenge is uploaded
-	if _, ok := uploadedChallenges[challengeName]; !ok {
+	if !ok {
 		log.Printf("Challenge %s not found in uploaded challenges\n", challengeName)
 		return "", errors.New("challenge not found in uploaded challenges")
 	}
 
 	// Check if 0 (deleted)
-	if uploadedChallenges[challengeName] == "0" {
+	if uploadedChallenge == "0" {
 		return "0", nil
 	}
 
-	if uploadedChallenges[challengeName] == "" {
+	if uploadedChallenge == "" {
 		return "0", nil
 	}
 
-	return uploadedChallenges[challengeName], nil
+	return uploadedChallenge, nil
 }
 
 func setUploadedCTFdChallenge(challengeName string, challengeID int) error {
-	// Get configmap for uploaded challenges
-	data, err := getUploadedCTFdChallenges()
+	// Set challenge ID in state store
+	err := challengeStore.Set(challengeName, strconv.Itoa(challengeID))
 	if err != nil {
-		log.Println("Error getting CTFd challenges configmap:", err)
-		return errors.New("error getting CTFd challenges configmap")
+		log.Println("Error updating CTFd challenges state:", err)
+		return errors.New("error updating CTFd challenges state")
 	}
 
-	// Set challenge ID in configmap
-	data[challengeName] = strconv.Itoa(challengeID)
+	return nil
+}
 
-	// Update configmap
-	err = updateConfigMap(getNamespace(), CTFDCHALLENGESCONFIGMAP, data)
+// Get the commit the files of a challenge were last synced at, or an empty string if the challenge is not pinned.
+// A missing challenge-commits configmap means no challenge has been pinned yet.
+func getSyncedChallengeCommit(challengeName string) (string, error) {
+	commit, _, err := commitStore.Get(challengeName)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
 	if err != nil {
-		log.Println("Error updating CTFd challenges configmap:", err)
-		return errors.New("error updating CTFd challenges configmap")
+		log.Printf("Error getting synced commit of challenge %s: %s\n", challengeName, err)
+		return "", err
 	}
+	return commit, nil
+}
 
+// Record the commit the files of a challenge were synced at, removing it if the challenge is not pinned
+func setSyncedChallengeCommit(challengeName string, commit string) error {
+	if commit == "" {
+		existing, err := getSyncedChallengeCommit(challengeName)
+		if err != nil || existing == "" {
+			return err
+		}
+		return commitStore.Delete(challengeName)
+	}
+
+	err := commitStore.Set(challengeName, commit)
+	if err != nil {
+		log.Printf("Error storing synced commit of challenge %s: %s\n", challengeName, err)
+		return err
+	}
 	return nil
 }
 
 func deleteUploadedCTFdChallenge(challengeName string) error {
-	// Get configmap for uploaded challenges
-	data, err := getUploadedCTFdChallenges()
+	// Mark challenge as deleted in state store
+	err := challengeStore.Set(challengeName, "0")
 	if err != nil {
-		log.Println("Error getting CTFd challenges configmap:", err)
-		return errors.New("error getting CTFd challenges configmap")
+		log.Println("Error updating CTFd challenges state:", err)
+		return errors.New("error updating CTFd challenges state")
 	}
 
-	// Set challenge ID in configmap
-	data[challengeName] = "0"
+	return nil
+}
 
-	// Update configmap
-	err = updateConfigMap(getNamespace(), CTFDCHALLENGESCONFIGMAP, data)
+// Get the names of the files of a challenge in the repository
+func getChallengeFileNames(challenge *ChallengeConfig) ([]string, error) {
+	source, err := challengeFileSource(challenge)
+	if err != nil {
+		return nil, err
+	}
+	files, err := source.ListFiles(challengeRef(challenge), filesDirPath(challenge))
 	if err != nil {
-		log.Println("Error updating CTFd challenges configmap:", err)
-		return errors.New("error updating CTFd challenges configmap")
+		return nil, err
 	}
 
-	return nil
+	names := []string{}
+	for _, file := range files {
+		if file.Name == "" || file.Name == ".gitignore" || file.Name == ".gitkeep" {
+			continue
+		}
+		names = append(names, ctfdFileName(file.Name))
+	}
+
+	return names, nil
+}
+
+// Characters removed from file names by CTFd
+var ctfdFileNameStripPattern = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Get the name CTFd stores an uploaded file under, matching secure_filename of Werkzeug.
+// Non-ASCII characters are decomposed or removed, whitespace is replaced with underscores, and other special characters are removed.
+func ctfdFileName(name string) string {
+	name = norm.NFKD.String(name)
+	name = strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.ReplaceAll(name, "/", " ")
+
+	// Python splits on ASCII whitespace and the separator control characters
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || (r >= 0x1c && r <= 0x1f)
+	})
+	name = ctfdFileNameStripPattern.ReplaceAllString(strings.Join(fields, "_"), "")
+	return strings.Trim(name, "._")
 }
 
-func uploadCTFdChallengeFile(id int, challenge *ChallengeConfig, client *ctfd.Client) (int, error) {
+// Sync the files of a challenge to CTFd.
+// Files are matched by the name CTFd stores them under, and only files that were added, changed or removed are uploaded or deleted, so download links of unchanged files keep working.
+// Changed files are replaced only once the new version is uploaded, so players always have a file to download.
+func syncCTFdChallengeFiles(id int, challenge *ChallengeConfig, client *ctfd.Client) error {
 	// Get files
-	files, err := getGithubDirContents(getGithubRepo(), getGithubBranch(), filesDirPath(challenge))
+	source, err := challengeFileSource(challenge)
+	if err != nil {
+		log.Printf("Error getting file source: %s\n", err)
+		return err
+	}
+	files, err := source.ListFiles(challengeRef(challenge), filesDirPath(challenge))
 	if err != nil {
 		log.Printf("Error getting directory contents (err): %s\n", err)
-		return 0, err
+		return err
 	}
 
-	filesContent := make([]*ctfd.InputFile, 0)
-	if files != nil && len(files) > 0 {
-		for _, file := range files {
-			if file.GetName() == ".gitignore" || file.GetName() == ".gitkeep" {
-				continue
+	// Get the files in CTFd by name. The file list does not include the hash, so each file is fetched individually
+	ctfdFiles, err := client.GetChallengeFiles(id)
+	if err != nil {
+		log.Printf("Error getting challenge files: %s\n", err)
+		return err
+	}
+	existing := map[string]*ctfd.File{}
+	for _, item := range ctfdFiles {
+		file, err := client.GetFile(strconv.Itoa(item.ID))
+		if err != nil {
+			log.Printf("Error getting file %d: %s\n", item.ID, err)
+			return err
+		}
+
+		name := path.Base(file.Location)
+		if _, duplicate := existing[name]; duplicate {
+			// Only keep a single file per name
+			if err := deleteCTFdFile(file, client); err != nil {
+				return err
 			}
+			continue
+		}
+		existing[name] = file
+	}
 
-			// Get file content
-			path := (filesDirPath(challenge) + "/" + file.GetName())
-			data, error := getGithubFileBytes(getGithubRepo(), getGithubBranch(), path)
+	filesContent := make([]*ctfd.InputFile, 0)
+	kept := map[string]bool{}
+	replaced := []*ctfd.File{}
+	for _, file := range files {
+		if file.Name == "" || file.Name == ".gitignore" || file.Name == ".gitkeep" {
+			continue
+		}
 
-			if error != nil {
-				log.Printf("Error getting file content: %s\n", error)
-			}
+		// Get file content
+		data, err := source.ReadFile(challengeRef(challenge), file.Path)
+		if err != nil {
+			log.Printf("Error getting file content: %s\n", err)
+			return fmt.Errorf("error getting file %s: %w", file.Name, err)
+		}
 
-			// Convert to format
-			if error == nil && data != nil && file.GetName() != "" {
-				filesContent = append(filesContent, &ctfd.InputFile{
-					Name:    file.GetName(),
-					Content: []byte(*data),
-				})
-			}
+		// Compare with the sha1sum of the file in CTFd. Older CTFd versions do not store it, so the file is always replaced
+		name := ctfdFileName(file.Name)
+		current, exists := existing[name]
+		sum := sha1.Sum(data)
+		if exists && current.SHA1sum != "" && current.SHA1sum == hex.EncodeToString(sum[:]) {
+			kept[name] = true
+			continue
 		}
-	}
 
-	// Print files
-	if len(filesContent) > 0 {
-		for _, file := range filesContent {
-			log.Printf("File: %s\n", file.Name)
-			// log.Printf("Content: %s\n", string(file.Content))
+		if exists {
+			log.Printf("File %s changed, replacing it\n", file.Name)
+			replaced = append(replaced, current)
+		} else {
+			log.Printf("File %s added\n", file.Name)
 		}
+		kept[name] = true
+
+		filesContent = append(filesContent, &ctfd.InputFile{
+			Name:    file.Name,
+			Content: data,
+		})
 	}
 
 	// Upload files
@@ -190,52 +288,90 @@ func uploadCTFdChallengeFile(id int, challenge *ChallengeConfig, client *ctfd.Cl
 		})
 		if err != nil {
 			log.Printf("Error uploading files: %s\n", err)
-			return 0, err
+			return err
 		}
 	}
 
-	return id, nil
-}
-
-func uploadCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int, error) {
-	state := "visible"
-	if !challenge.Challenge.Enabled {
-		state = "hidden"
+	// Delete the previous versions of the replaced files
+	for _, file := range replaced {
+		if err := deleteCTFdFile(file, client); err != nil {
+			return err
+		}
 	}
 
-	challType := "dynamic"
-	if challenge.Challenge.Type == "instanced" {
-		challType = "kubectf"
-	}
-
-	// Cut the first two lines from the description
-	description := challenge.Description
-	if challenge.Description != "" {
-		// Split the description into lines
-		lines := strings.Split(challenge.Description, "\n")
-		// Check if there are at least two lines
-		if len(lines) > 2 {
-			// Join the lines after the first two
-			description = strings.Join(lines[2:], "\n")
+	// Delete the files that were removed from the repository
+	for name, file := range existing {
+		if kept[name] {
+			continue
+		}
+		log.Printf("File %s removed\n", name)
+		if err := deleteCTFdFile(file, client); err != nil {
+			return err
 		}
 	}
 
+	return nil
+}
+
+func deleteCTFdFile(file *ctfd.File, client *ctfd.Client) error {
+	err := client.DeleteFile(strconv.Itoa(file.ID))
+	if err != nil {
+		log.Printf("Error deleting file: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+func uploadCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int, error) {
 	challengeMappingMap, err := getMappingMap(getNamespace())
 	if err != nil {
 		log.Println("Error getting mapping map:", err)
 		return 0, errors.New("error getting mapping map")
 	}
 
+	// File links are resolved once the files are uploaded
+	rendered, err := renderCTFdChallenge(challenge, challengeMappingMap, nil)
+	if err != nil {
+		log.Printf("Error rendering challenge: %s\n", err)
+		return 0, err
+	}
+
+	requirements, err := resolveChallengeRequirements(challenge)
+	if err != nil {
+		log.Printf("Error resolving prerequisites: %s\n", err)
+		return 0, err
+	}
+
+	// Validate flags and hints before anything is changed in CTFd
+	err = validateChallengeFlags(challenge)
+	if err != nil {
+		log.Printf("Error validating flags: %s\n", err)
+		return 0, err
+	}
+	err = validateChallengeHints(challenge)
+	if err != nil {
+		log.Printf("Error validating hints: %s\n", err)
+		return 0, err
+	}
+
+	value, initial, decay, minimum, function := rendered.scoringParams(nil)
 	params := ctfd.PostChallengesParams{
-		Name:           challenge.Challenge.Name,
-		Category:       getCategoryName(challenge, challengeMappingMap),
-		Description:    description,
-		Initial:        &challenge.Challenge.Points,
-		Decay:          &challenge.Challenge.Decay,
-		Minimum:        &challenge.Challenge.MinPoints,
-		State:          state,
-		Type:           challType,
-		ConnectionInfo: &challenge.Challenge.Connection,
+		Name:           rendered.Name,
+		Category:       rendered.Category,
+		Description:    rendered.Description,
+		Initial:        initial,
+		Decay:          decay,
+		Minimum:        minimum,
+		Function:       function,
+		State:          rendered.State,
+		Type:           rendered.Type,
+		ConnectionInfo: &rendered.Connection,
+		Attribution:    getAttributionParam(rendered, challengeMappingMap),
+		MaxAttempts:    &rendered.MaxAttempts,
+		Requirements:   requirements,
+	}
+	if value != nil {
+		params.Value = *value
 	}
 
 	var uploadedChallenge *ctfd.Challenge
@@ -276,40 +412,45 @@ func uploadCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		uploadedChallenge = ch
 	}
 
+	// Store the ID right away, so a failure in one of the following steps is retried as an update, instead of creating a duplicate challenge
+	err = setUploadedCTFdChallenge(challenge.Challenge.Slug, uploadedChallenge.ID)
+	if err != nil {
+		log.Printf("Error setting uploaded challenge: %s\n", err)
+		return 0, err
+	}
+
 	// Upload files
-	_, err = uploadCTFdChallengeFile(uploadedChallenge.ID, challenge, client)
+	err = syncCTFdChallengeFiles(uploadedChallenge.ID, challenge, client)
 	if err != nil {
 		log.Printf("Error uploading files: %s\n", err)
 		return 0, err
 	}
 
-	// Upload flag
-	for _, flag := range challenge.Challenge.Flag {
-		data := ""
-		if !flag.CaseSensitive {
-			data = "case_insensitive" // Use case_insensitive if not case sensitive
-		}
+	// Upload flags
+	err = uploadCTFdChallengeFlags(uploadedChallenge.ID, challenge, client)
+	if err != nil {
+		log.Printf("Error uploading flags: %s\n", err)
+		return 0, err
+	}
 
-		_, err = client.PostFlags(&ctfd.PostFlagsParams{
-			Challenge: uploadedChallenge.ID,
-			Content:   flag.Flag,
-			Type:      "static",
-			Data:      data,
-		})
-		if err != nil {
-			log.Printf("Error uploading flag: %s\n", err)
-			return 0, err
-		}
+	// Upload hints
+	err = syncCTFdChallengeHints(uploadedChallenge.ID, challenge, client)
+	if err != nil {
+		log.Printf("Error uploading hints: %s\n", err)
+		return 0, err
 	}
 
-	// Upload tags
-	if challenge.Challenge.Tags != nil && len(challenge.Challenge.Tags) > 0 {
-		for _, tag := range challenge.Challenge.Tags {
-			if tag == "" {
-				log.Println("Empty tag found, skipping upload")
-				continue
-			}
+	// Update the file links in the description and connection
+	err = syncCTFdChallengeFileLinks(uploadedChallenge.ID, challenge, challengeMappingMap, rendered, client)
+	if err != nil {
+		log.Printf("Error updating file links: %s\n", err)
+		return 0, err
+	}
 
+	// Upload tags
+	challengeTags := getChallengeTags(challenge, challengeMappingMap)
+	if len(challengeTags) > 0 {
+		for _, tag := range challengeTags {
 			_, err = client.PostTags(&ctfd.PostTagsParams{
 				Challenge: uploadedChallenge.ID,
 				Value:     tag,
@@ -322,10 +463,10 @@ func uploadCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		}
 	}
 
-	// Add to uploaded challenges
-	err = setUploadedCTFdChallenge(challenge.Challenge.Slug, uploadedChallenge.ID)
+	// Sync topics
+	err = syncCTFdChallengeTopics(uploadedChallenge.ID, challenge, challengeMappingMap, client)
 	if err != nil {
-		log.Printf("Error setting uploaded challenge: %s\n", err)
+		log.Printf("Error syncing topics: %s\n", err)
 		return 0, err
 	}
 
@@ -346,57 +487,76 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		return 0, err
 	}
 
-	// Check if challenge is uploaded
+	// Get the challenges in CTFd. Errors are returned instead of uploading, so a failed request never creates a duplicate challenge
 	challenges, err := getCTFdChallenges()
-	if err != nil || challenges == nil || len(challenges) == 0 {
-		return uploadCTFdChallenge(challenge, client)
+	if err != nil {
+		log.Printf("Error getting challenges: %s\n", err)
+		return 0, err
 	}
 
 	// Check if challenge is uploaded
-	found := false
+	var live *ctfd.Challenge
 	for _, ch := range challenges {
 		if ch.ID == challengeId {
-			found = true
+			live = ch
 			break
 		}
 	}
-	if !found {
+	if live == nil {
 		return uploadCTFdChallenge(challenge, client)
 	}
 
-	// Format state
-	state := "visible"
-	if !challenge.Challenge.Enabled {
-		state = "hidden"
+	challengeMappingMap, err := getMappingMap(getNamespace())
+	if err != nil {
+		log.Println("Error getting mapping map:", err)
+		return 0, errors.New("error getting mapping map")
 	}
 
-	// Cut the first two lines from the description
-	description := challenge.Description
-	if challenge.Description != "" {
-		// Split the description into lines
-		lines := strings.Split(challenge.Description, "\n")
-		// Check if there are at least two lines
-		if len(lines) > 2 {
-			// Join the lines after the first two
-			description = strings.Join(lines[2:], "\n")
-		}
+	// File links are resolved once the files are uploaded
+	rendered, err := renderCTFdChallenge(challenge, challengeMappingMap, nil)
+	if err != nil {
+		log.Printf("Error rendering challenge: %s\n", err)
+		return 0, err
 	}
 
-	challengeMappingMap, err := getMappingMap(getNamespace())
+	requirements, err := resolveChallengeRequirements(challenge)
 	if err != nil {
-		log.Println("Error getting mapping map:", err)
-		return 0, errors.New("error getting mapping map")
+		log.Printf("Error resolving prerequisites: %s\n", err)
+		return 0, err
+	}
+
+	// Validate flags and hints before anything is changed in CTFd
+	err = validateChallengeFlags(challenge)
+	if err != nil {
+		log.Printf("Error validating flags: %s\n", err)
+		return 0, err
+	}
+	err = validateChallengeHints(challenge)
+	if err != nil {
+		log.Printf("Error validating hints: %s\n", err)
+		return 0, err
+	}
+
+	// The type of a challenge can not be patched, so the challenge is re-created if it has no solves
+	if live.Type != "" && live.Type != rendered.Type {
+		return recreateCTFdChallenge(challengeId, live.Type, rendered.Type, challenge, client)
 	}
 
+	value, initial, decay, minimum, function := rendered.scoringParams(live)
 	params := ctfd.PatchChallengeParams{
-		Name:           challenge.Challenge.Name,
-		Category:       getCategoryName(challenge, challengeMappingMap),
-		Description:    description,
-		Initial:        &challenge.Challenge.Points,
-		Decay:          &challenge.Challenge.Decay,
-		Minimum:        &challenge.Challenge.MinPoints,
-		State:          state,
-		ConnectionInfo: &challenge.Challenge.Connection,
+		Name:           rendered.Name,
+		Category:       rendered.Category,
+		Description:    rendered.Description,
+		Value:          value,
+		Initial:        initial,
+		Decay:          decay,
+		Minimum:        minimum,
+		Function:       function,
+		State:          rendered.State,
+		ConnectionInfo: &rendered.Connection,
+		Attribution:    getAttributionParam(rendered, challengeMappingMap),
+		MaxAttempts:    &rendered.MaxAttempts,
+		Requirements:   requirements,
 	}
 
 	var uploadedChallenge *ctfd.Challenge
@@ -437,27 +597,10 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		uploadedChallenge = ch
 	}
 
-	// Get files from CTFd
-	ctfdFiles, err := client.GetChallengeFiles(challengeId)
-	if err != nil {
-		log.Printf("Error getting challenge files: %s\n", err)
-		return 0, err
-	}
-	if ctfdFiles != nil && len(ctfdFiles) > 0 {
-		for _, file := range ctfdFiles {
-			// Delete files
-			err = client.DeleteFile(strconv.Itoa(file.ID))
-			if err != nil {
-				log.Printf("Error deleting file: %s\n", err)
-				return 0, err
-			}
-		}
-	}
-
-	// Upload files
-	_, err = uploadCTFdChallengeFile(challengeId, challenge, client)
+	// Sync files
+	err = syncCTFdChallengeFiles(challengeId, challenge, client)
 	if err != nil {
-		log.Printf("Error uploading files: %s\n", err)
+		log.Printf("Error syncing files: %s\n", err)
 		return 0, err
 	}
 
@@ -479,23 +622,18 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		}
 	}
 
-	// Upload flag
-	for _, flag := range challenge.Challenge.Flag {
-		data := ""
-		if !flag.CaseSensitive {
-			data = "case_insensitive" // Use case_insensitive if not case sensitive
-		}
+	// Upload flags
+	err = uploadCTFdChallengeFlags(uploadedChallenge.ID, challenge, client)
+	if err != nil {
+		log.Printf("Error uploading flags: %s\n", err)
+		return 0, err
+	}
 
-		_, err = client.PostFlags(&ctfd.PostFlagsParams{
-			Challenge: uploadedChallenge.ID,
-			Content:   flag.Flag,
-			Type:      "static",
-			Data:      data,
-		})
-		if err != nil {
-			log.Printf("Error uploading flag: %s\n", err)
-			return 0, err
-		}
+	// Sync hints
+	err = syncCTFdChallengeHints(uploadedChallenge.ID, challenge, client)
+	if err != nil {
+		log.Printf("Error syncing hints: %s\n", err)
+		return 0, err
 	}
 
 	// Remove existing tags
@@ -520,14 +658,17 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		log.Println("No tags found to delete")
 	}
 
-	// Upload tags
-	if challenge.Challenge.Tags != nil && len(challenge.Challenge.Tags) > 0 {
-		for _, tag := range challenge.Challenge.Tags {
-			if tag == "" {
-				log.Println("Empty tag found, skipping upload")
-				continue
-			}
+	// Update the file links in the description and connection
+	err = syncCTFdChallengeFileLinks(uploadedChallenge.ID, challenge, challengeMappingMap, rendered, client)
+	if err != nil {
+		log.Printf("Error updating file links: %s\n", err)
+		return 0, err
+	}
 
+	// Upload tags
+	challengeTags := getChallengeTags(challenge, challengeMappingMap)
+	if len(challengeTags) > 0 {
+		for _, tag := range challengeTags {
 			_, err = client.PostTags(&ctfd.PostTagsParams{
 				Challenge: uploadedChallenge.ID,
 				Value:     tag,
@@ -540,6 +681,13 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 		}
 	}
 
+	// Sync topics
+	err = syncCTFdChallengeTopics(uploadedChallenge.ID, challenge, challengeMappingMap, client)
+	if err != nil {
+		log.Printf("Error syncing topics: %s\n", err)
+		return 0, err
+	}
+
 	// Add to uploaded challenges
 	err = setUploadedCTFdChallenge(challenge.Challenge.Slug, uploadedChallenge.ID)
 	if err != nil {
@@ -550,7 +698,47 @@ func updateCTFdChallenge(challenge *ChallengeConfig, client *ctfd.Client) (int,
 	return uploadedChallenge.ID, nil
 }
 
+// Re-create a challenge to change its type. Refuses if the challenge has solves, as they would be lost.
+func recreateCTFdChallenge(challengeID int, currentType string, desiredType string, challenge *ChallengeConfig, client *ctfd.Client) (int, error) {
+	live, err := client.GetChallenge(challengeID)
+	if err != nil {
+		log.Printf("Error getting challenge %d: %s\n", challengeID, err)
+		return 0, err
+	}
+	if live.Solves > 0 {
+		return 0, fmt.Errorf("challenge %s has %d solves, refusing to change its type from %s to %s. Delete the challenge in CTFd to change its scoring", challenge.Challenge.Slug, live.Solves, currentType, desiredType)
+	}
+
+	log.Printf("Re-creating challenge %s (%d) to change its type from %s to %s\n", challenge.Challenge.Slug, challengeID, currentType, desiredType)
+	err = client.DeleteChallenge(challengeID)
+	if err != nil {
+		log.Printf("Error deleting challenge %d: %s\n", challengeID, err)
+		return 0, err
+	}
+
+	id, err := uploadCTFdChallenge(challenge, client)
+
+	// The challenges requiring this challenge still point at the deleted ID. The new ID is stored as soon as the challenge is created, even if a later step fails
+	if uploadedChallengeID, _ := getUploadedCTFdChallenge(challenge.Challenge.Slug); uploadedChallengeID != "" && uploadedChallengeID != strconv.Itoa(challengeID) {
+		resyncDependentChallenges(challenge.Challenge.Slug)
+	}
+
+	return id, err
+}
+
+// Locks of the challenges being synced, by slug, so the sync worker, the reconciler and the API never sync the same challenge at once
+var challengeSyncLocks sync.Map
+
+// Lock the challenge for syncing, returning the function that unlocks it
+func lockChallengeSync(slug string) func() {
+	lock, _ := challengeSyncLocks.LoadOrStore(slug, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
 func updateOrCreateCTFdChallenge(challenge *ChallengeConfig) (int, error) {
+	defer lockChallengeSync(challenge.Challenge.Slug)()
+
 	// Get client
 	client, err := getCTFdClient()
 	if err != nil {
@@ -575,10 +763,18 @@ func updateOrCreateCTFdChallenge(challenge *ChallengeConfig) (int, error) {
 		return 0, err
 	}
 
+	// Record the commit the files were synced at
+	err = setSyncedChallengeCommit(challenge.Challenge.Slug, challenge.Commit)
+	if err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
 func uploadChallenge(challenge *ChallengeConfig) (*int, error) {
+	defer lockChallengeSync(challenge.Challenge.Slug)()
+
 	// Get client
 	client, err := getCTFdClient()
 	if err != nil {
@@ -598,7 +794,9 @@ func uploadChallenge(challenge *ChallengeConfig) (*int, error) {
 	return &id, nil
 }
 
-func disableCTFdChallenge(challenge *ChallengeConfig) error {
+func disableCTFdChallenge(challengeSlug string) error {
+	defer lockChallengeSync(challengeSlug)()
+
 	client, err := getCTFdClient()
 	if err != nil {
 		log.Printf("Error getting CTFd client: %s\n", err)
@@ -606,9 +804,9 @@ func disableCTFdChallenge(challenge *ChallengeConfig) error {
 	}
 
 	// Get uploaded challenge ID
-	uploadedChallengeID, err := getUploadedCTFdChallenge(challenge.Challenge.Slug)
+	uploadedChallengeID, err := getUploadedCTFdChallenge(challengeSlug)
 	if err != nil || uploadedChallengeID == "" || uploadedChallengeID == "0" {
-		log.Printf("Challenge %s not found in uploaded challenges, nothing to disable (error: %s)\n", challenge.Challenge.Slug, err)
+		log.Printf("Challenge %s not found in uploaded challenges, nothing to disable (error: %s)\n", challengeSlug, err)
 		return nil
 	}
 
@@ -623,14 +821,78 @@ func disableCTFdChallenge(challenge *ChallengeConfig) error {
 		State: "hidden", // Set state to hidden
 	}
 
-	log.Printf("Disabling challenge %s (%d) in CTFd...\n", challenge.Challenge.Slug, uploadedChallengeIDInt)
+	log.Printf("Disabling challenge %s (%d) in CTFd...\n", challengeSlug, uploadedChallengeIDInt)
 	updateChallenge := &ctfd.Challenge{}
 	err = client.Patch(fmt.Sprintf("/challenges/%d", uploadedChallengeIDInt), &params, updateChallenge)
 	if err != nil {
 		log.Printf("Error disabling challenge in CTFd: %s\n", err)
 		return err
 	}
-	log.Printf("Challenge %s disabled (hidden) in CTFd\n", challenge.Challenge.Slug)
+	log.Printf("Challenge %s disabled (hidden) in CTFd\n", challengeSlug)
 
 	return nil
 }
+
+func deleteCTFdChallenge(challengeSlug string) error {
+	defer lockChallengeSync(challengeSlug)()
+
+	client, err := getCTFdClient()
+	if err != nil {
+		log.Printf("Error getting CTFd client: %s\n", err)
+		return err
+	}
+
+	// Get uploaded challenge ID
+	uploadedChallengeID, err := getUploadedCTFdChallenge(challengeSlug)
+	if err != nil || uploadedChallengeID == "" || uploadedChallengeID == "0" {
+		log.Printf("Challenge %s not found in uploaded challenges, nothing to delete (error: %s)\n", challengeSlug, err)
+		return nil
+	}
+
+	// Convert uploadedChallengeID to int
+	uploadedChallengeIDInt, err := strconv.Atoi(uploadedChallengeID)
+	if err != nil {
+		log.Printf("Error converting uploaded challenge ID %s to int: %s\n", uploadedChallengeID, err)
+		return err
+	}
+
+	log.Printf("Deleting challenge %s (%d) in CTFd...\n", challengeSlug, uploadedChallengeIDInt)
+	err = client.DeleteChallenge(uploadedChallengeIDInt)
+	if err != nil {
+		log.Printf("Error deleting challenge in CTFd: %s\n", err)
+		return err
+	}
+	log.Printf("Challenge %s deleted in CTFd\n", challengeSlug)
+
+	// Overrides adopted from CTFd no longer apply to a new challenge with the same slug
+	if err := clearChallengeOverrides(challengeSlug); err != nil {
+		log.Printf("Error clearing overrides for challenge %s: %s\n", challengeSlug, err)
+	}
+	if err := setSyncedChallengeCommit(challengeSlug, ""); err != nil {
+		log.Printf("Error clearing synced commit for challenge %s: %s\n", challengeSlug, err)
+	}
+
+	return deleteUploadedCTFdChallenge(challengeSlug)
+}
+
+// Re-render the description and connection with the uploaded files of the challenge, and update them if they link to files
+func syncCTFdChallengeFileLinks(challengeID int, challenge *ChallengeConfig, mappingMap MappingMap, rendered *RenderedChallenge, client *ctfd.Client) error {
+	files, err := client.GetChallengeFiles(challengeID)
+	if err != nil {
+		return err
+	}
+
+	linked, err := renderCTFdChallenge(challenge, mappingMap, files)
+	if err != nil {
+		return err
+	}
+	if linked.Description == rendered.Description && linked.Connection == rendered.Connection {
+		return nil
+	}
+
+	chall := &ctfd.Challenge{}
+	return client.Patch(fmt.Sprintf("/challenges/%d", challengeID), &CTFdChallengeTextParams{
+		Description:    linked.Description,
+		ConnectionInfo: linked.Connection,
+	}, &chall)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCTFdFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "challenge.zip", want: "challenge.zip"},
+		{name: "my file.zip", want: "my_file.zip"},
+		{name: "  spaced \t out  .txt", want: "spaced_out_.txt"},
+		{name: "café.txt", want: "cafe.txt"},
+		{name: "flag (1).txt", want: "flag_1.txt"},
+		{name: "../../etc/passwd", want: "etc_passwd"},
+		{name: ".hidden", want: "hidden"},
+		{name: "日本語.bin", want: "bin"},
+		{name: "a-b_c.tar.gz", want: "a-b_c.tar.gz"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ctfdFileName(test.name); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -24,89 +24,59 @@ func getCTFdPages() ([]*ctfd.Page, error) {
 }
 
 func getUploadedCTFdPages() (map[string]string, error) {
-	// Get configmap for uploaded pages
-	configMap, err := getConfigMap(getNamespace(), CTFDPAGESCONFIGMAP)
+	// Get uploaded pages from the state store
+	data, err := pageStore.List()
 	if err != nil {
-		log.Println("Error getting CTFd pages configmap:", err)
-		return nil, errors.New("error getting CTFd pages configmap")
+		log.Println("Error getting CTFd pages state:", err)
+		return nil, errors.New("error getting CTFd pages state")
 	}
 
-	if configMap == nil {
-		log.Println("CTFd pages configmap is nil")
-		return nil, errors.New("CTFd pages configmap is nil")
-	}
-
-	if configMap.Data == nil {
-		log.Println("CTFd pages configmap data is nil")
-		return make(map[string]string), nil
-	}
-
-	return configMap.Data, nil
+	return data, nil
 }
 
 func getUploadedCTFdPage(slug string) (string, error) {
-	// Get uploaded pages
-	uploadedPages, err := getUploadedCTFdPages()
+	// Get uploaded page
+	uploadedPage, ok, err := pageStore.Get(slug)
 	if err != nil {
 		log.Println("Error getting uploaded pages:", err)
 		return "", errors.New("error getting uploaded pages")
 	}
 
 	// Check if page is uploaded
-	if _, ok := uploadedPages[slug]; !ok {
+	if !ok {
 		log.Printf("Page %s not found in uploaded pages\n", slug)
 		return "0", errors.New("page not found in uploaded pages")
 	}
 
 	// Check if 0 (deleted)
-	if uploadedPages[slug] == "0" {
+	if uploadedPage == "0" {
 		return "0", nil
 	}
 
-	if uploadedPages[slug] == "" {
+	if uploadedPage == "" {
 		return "0", nil
 	}
 
-	return uploadedPages[slug], nil
+	return uploadedPage, nil
 }
 
 func setUploadedCTFdPage(pageSlug string, pageID int) error {
-	// Get configmap for uploaded pages
-	data, err := getUploadedCTFdPages()
-	if err != nil {
-		log.Println("Error getting CTFd pages configmap:", err)
-		return errors.New("error getting CTFd pages configmap")
-	}
-
-	// Set page ID in configmap
-	data[pageSlug] = strconv.Itoa(pageID)
-
-	// Update configmap
-	err = updateConfigMap(getNamespace(), CTFDPAGESCONFIGMAP, data)
+	// Set page ID in state store
+	err := pageStore.Set(pageSlug, strconv.Itoa(pageID))
 	if err != nil {
-		log.Println("Error updating CTFd pages configmap:", err)
-		return errors.New("error updating CTFd pages configmap")
+		log.Println("Error updating CTFd pages state:", err)
+		return errors.New("error updating CTFd pages state")
 	}
 
 	return nil
 }
 
 func deleteUploadedCTFdPage(pageSlug string) error {
-	// Get configmap for uploaded pages
-	data, err := getUploadedCTFdPages()
-	if err != nil {
-		log.Println("Error getting CTFd pages configmap:", err)
-		return errors.New("error getting CTFd pages configmap")
-	}
-
-	// Set page ID in configmap
-	data[pageSlug] = "0"
-
-	// Update configmap
-	err = updateConfigMap(getNamespace(), CTFDPAGESCONFIGMAP, data)
+	// Mark page as deleted in state store
+	err := pageStore.Set(pageSlug, "0")
 	if err != nil {
-		log.Println("Error updating CTFd pages configmap:", err)
-		return errors.New("error updating CTFd pages configmap")
+		log.Println("Error updating CTFd pages state:", err)
+		return errors.New("error updating CTFd pages state")
 	}
 
 	return nil
@@ -17,6 +17,9 @@ import (
 const CTFDACCESSCONFIGMAP = "ctfd-access-token"
 const CTFDCHALLENGESCONFIGMAP = "ctfd-challenges"
 const CTFDPAGESCONFIGMAP = "ctfd-pages"
+const CONFIGMAPHASHSETCONFIGMAP = "challenge-configmap-hashset"
+const CHALLENGEOVERRIDESCONFIGMAP = "challenge-overrides"
+const CHALLENGECOMMITSCONFIGMAP = "challenge-commits"
 
 type CTFdSetupParamsInputFile struct {
 	Name    string `json:"name"`
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ATX style level 1 heading, such as "# Title"
+var atxHeadingPattern = regexp.MustCompile(`^ {0,3}#(?:[ \t]+|$)`)
+
+// Setext style level 1 heading underline, such as "====="
+var setextUnderlinePattern = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+
+// Description of a challenge, split into its front-matter and markdown body
+type RenderedDescription struct {
+	FrontMatter map[string]any
+	Body        string
+}
+
+// Get the path of the description file of the challenge in the repository
+func descriptionFilePath(challenge *ChallengeConfig) string {
+	return path.Join(challenge.Path, challenge.Challenge.DescriptionLocation)
+}
+
+// Get the markdown description of the challenge, from the description file in the repository if description_location is set
+func getChallengeDescription(challenge *ChallengeConfig) (string, error) {
+	if challenge.Challenge.DescriptionLocation == "" {
+		return challenge.Description, nil
+	}
+
+	source, err := challengeFileSource(challenge)
+	if err != nil {
+		return "", err
+	}
+	description, err := source.ReadFile(challengeRef(challenge), descriptionFilePath(challenge))
+	if err != nil {
+		return "", fmt.Errorf("error getting description file %s: %w", challenge.Challenge.DescriptionLocation, err)
+	}
+
+	return string(description), nil
+}
+
+// Render a challenge description for CTFd.
+// Removes the optional YAML front-matter, and the leading level 1 heading if removeHeader is set and the description starts with one.
+func renderDescription(description string, removeHeader bool) *RenderedDescription {
+	description = strings.ReplaceAll(description, "\r\n", "\n")
+
+	frontMatter, body := splitFrontMatter(description)
+
+	if removeHeader {
+		body = removeLeadingHeading(body)
+	}
+
+	return &RenderedDescription{
+		FrontMatter: frontMatter,
+		Body:        body,
+	}
+}
+
+// Split the YAML front-matter, enclosed in "---" lines at the start of the description, from the body.
+// The block is only front-matter if it is a YAML mapping, otherwise the description starts with a thematic break and is returned as is.
+func splitFrontMatter(description string) (map[string]any, string) {
+	lines := strings.Split(description, "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return map[string]any{}, description
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if line != "---" && line != "..." {
+			continue
+		}
+
+		var frontMatter map[string]any
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:i], "\n")), &frontMatter); err != nil || frontMatter == nil {
+			break
+		}
+		return frontMatter, strings.Join(lines[i+1:], "\n")
+	}
+
+	return map[string]any{}, description
+}
+
+// Remove the leading level 1 heading of the markdown, if it starts with one
+func removeLeadingHeading(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+
+	// Skip leading blank lines
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return markdown
+	}
+
+	end := -1
+	switch {
+	case atxHeadingPattern.MatchString(lines[start]):
+		end = start + 1
+	case start+1 < len(lines) && setextUnderlinePattern.MatchString(lines[start+1]):
+		end = start + 2
+	}
+	if end < 0 {
+		return markdown
+	}
+
+	// Skip blank lines following the heading
+	for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+
+	return strings.Join(lines[end:], "\n")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveLeadingHeading(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "atx heading",
+			markdown: "# Title\n\nBody",
+			want:     "Body",
+		},
+		{
+			name:     "indented atx heading after blank lines",
+			markdown: "\n\n   # Title\nBody",
+			want:     "Body",
+		},
+		{
+			name:     "empty atx heading",
+			markdown: "#\nBody",
+			want:     "Body",
+		},
+		{
+			name:     "setext heading",
+			markdown: "Title\n=====\n\nBody",
+			want:     "Body",
+		},
+		{
+			name:     "level 2 heading",
+			markdown: "## Title\n\nBody",
+			want:     "## Title\n\nBody",
+		},
+		{
+			name:     "setext level 2 heading",
+			markdown: "Title\n-----\n\nBody",
+			want:     "Title\n-----\n\nBody",
+		},
+		{
+			name:     "hashtag",
+			markdown: "#hashtag\n\nBody",
+			want:     "#hashtag\n\nBody",
+		},
+		{
+			name:     "code block",
+			markdown: "    # Title\n\nBody",
+			want:     "    # Title\n\nBody",
+		},
+		{
+			name:     "heading after text",
+			markdown: "Body\n\n# Title",
+			want:     "Body\n\n# Title",
+		},
+		{
+			name:     "only a heading",
+			markdown: "# Title",
+			want:     "",
+		},
+		{
+			name:     "blank",
+			markdown: "\n\n",
+			want:     "\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := removeLeadingHeading(test.markdown); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	tests := []struct {
+		name         string
+		description  string
+		removeHeader bool
+		frontMatter  map[string]any
+		body         string
+	}{
+		{
+			name:        "plain",
+			description: "# Title\n\nBody",
+			frontMatter: map[string]any{},
+			body:        "# Title\n\nBody",
+		},
+		{
+			name:         "remove header",
+			description:  "# Title\r\n\r\nBody",
+			removeHeader: true,
+			frontMatter:  map[string]any{},
+			body:         "Body",
+		},
+		{
+			name:         "front-matter",
+			description:  "---\nauthor: someone\npoints: 100\n---\n# Title\n\nBody",
+			removeHeader: true,
+			frontMatter:  map[string]any{"author": "someone", "points": float64(100)},
+			body:         "Body",
+		},
+		{
+			name:        "front-matter closed with dots",
+			description: "---\nauthor: someone\n...\nBody",
+			frontMatter: map[string]any{"author": "someone"},
+			body:        "Body",
+		},
+		{
+			name:        "empty block",
+			description: "---\n---\nBody",
+			frontMatter: map[string]any{},
+			body:        "---\n---\nBody",
+		},
+		{
+			name:        "thematic break",
+			description: "---\nBody",
+			frontMatter: map[string]any{},
+			body:        "---\nBody",
+		},
+		{
+			name:        "thematic breaks around text",
+			description: "---\nIntroduction\n\n---\nBody",
+			frontMatter: map[string]any{},
+			body:        "---\nIntroduction\n\n---\nBody",
+		},
+		{
+			name:        "thematic breaks around a list",
+			description: "---\n- first\n- second\n---\nBody",
+			frontMatter: map[string]any{},
+			body:        "---\n- first\n- second\n---\nBody",
+		},
+		{
+			name:        "invalid YAML",
+			description: "---\nauthor: [\n---\nBody",
+			frontMatter: map[string]any{},
+			body:        "---\nauthor: [\n---\nBody",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered := renderDescription(test.description, test.removeHeader)
+			if !reflect.DeepEqual(rendered.FrontMatter, test.frontMatter) {
+				t.Errorf("front-matter = %v, want %v", rendered.FrontMatter, test.frontMatter)
+			}
+			if rendered.Body != test.body {
+				t.Errorf("body = %q, want %q", rendered.Body, test.body)
+			}
+		})
+	}
+}
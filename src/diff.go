@@ -0,0 +1,259 @@
+package main
+
+import (
+	"log"
+	"path"
+	"slices"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+// Difference between the live value in CTFd and the value desired by the configmap
+type FieldDiff struct {
+	Field   string `json:"field"`
+	Current any    `json:"current"`
+	Desired any    `json:"desired"`
+}
+
+// Get the fields where the live CTFd challenge differs from the rendered challenge config
+func diffCTFdChallenge(challenge *ChallengeConfig, live *ctfd.Challenge, client *ctfd.Client, mappingMap MappingMap) ([]FieldDiff, error) {
+	diffs := []FieldDiff{}
+	add := func(field string, current any, desired any) {
+		diffs = append(diffs, FieldDiff{Field: field, Current: current, Desired: desired})
+	}
+
+	// The files are needed to render file links in the description and connection
+	ctfdFiles, err := client.GetChallengeFiles(live.ID)
+	if err != nil {
+		log.Printf("Error getting files for challenge %d: %s\n", live.ID, err)
+		return nil, err
+	}
+
+	rendered, err := renderCTFdChallenge(challenge, mappingMap, ctfdFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if live.Name != rendered.Name {
+		add("name", live.Name, rendered.Name)
+	}
+	if live.Category != rendered.Category {
+		add("category", live.Category, rendered.Category)
+	}
+	if live.Description != rendered.Description {
+		add("description", live.Description, rendered.Description)
+	}
+	if live.State != rendered.State {
+		add("state", live.State, rendered.State)
+	}
+	if live.Type != rendered.Type {
+		add("type", live.Type, rendered.Type)
+	}
+	if rendered.Type == "standard" {
+		if live.Value != rendered.Points {
+			add("points", live.Value, rendered.Points)
+		}
+	} else {
+		if !equalIntPointer(live.Initial, rendered.Points) {
+			add("points", intPointerValue(live.Initial), rendered.Points)
+		}
+		if !equalIntPointer(live.Decay, rendered.Decay) {
+			add("decay", intPointerValue(live.Decay), rendered.Decay)
+		}
+		if !equalIntPointer(live.Minimum, rendered.MinPoints) {
+			add("min_points", intPointerValue(live.Minimum), rendered.MinPoints)
+		}
+		// Older CTFd versions do not return the decay function, in which case it is not sent either
+		if live.Function != nil && *live.Function != rendered.Function {
+			add("function", *live.Function, rendered.Function)
+		}
+	}
+	// Older CTFd versions do not support the attribution, so it is only managed when the author is published as attribution
+	if live.Attribution != nil && getAttributionParam(rendered, mappingMap) != nil && *live.Attribution != rendered.Attribution {
+		add("attribution", *live.Attribution, rendered.Attribution)
+	}
+	if maxAttempts := intPointerValue(live.MaxAttempts); maxAttempts != rendered.MaxAttempts {
+		add("max_attempts", maxAttempts, rendered.MaxAttempts)
+	}
+	if connection := stringPointerValue(live.ConnectionInfo); connection != rendered.Connection {
+		add("connection", connection, rendered.Connection)
+	}
+
+	// Compare prerequisites
+	requirements, err := client.GetChallengeRequirements(live.ID)
+	if err != nil {
+		log.Printf("Error getting requirements for challenge %d: %s\n", live.ID, err)
+	} else {
+		livePrerequisites := []int{}
+		liveAnonymize := false
+		if requirements != nil {
+			livePrerequisites = requirements.Prerequisites
+			liveAnonymize = requirements.Anonymize != nil && *requirements.Anonymize
+		}
+		desiredPrerequisites, err := resolvePrerequisiteIDs(challenge)
+		if err != nil {
+			log.Printf("Error resolving prerequisites for challenge %s: %s\n", challenge.Challenge.Slug, err)
+		} else if !equalIntSets(livePrerequisites, desiredPrerequisites) {
+			add("prerequisites", livePrerequisites, desiredPrerequisites)
+		}
+		if len(desiredPrerequisites) > 0 && liveAnonymize != challenge.Challenge.AnonymizePrerequisites {
+			add("anonymize_prerequisites", liveAnonymize, challenge.Challenge.AnonymizePrerequisites)
+		}
+	}
+
+	// Compare flags
+	flags, err := client.GetChallengeFlags(live.ID)
+	if err != nil {
+		log.Printf("Error getting flags for challenge %d: %s\n", live.ID, err)
+	} else {
+		liveFlags := []string{}
+		for _, flag := range flags {
+			liveFlags = append(liveFlags, formatFlag(flag.Type, flag.Data, flag.Content))
+		}
+		desiredFlags := []string{}
+		for _, flag := range challenge.Challenge.Flag {
+			content, flagType, data, err := renderChallengeFlag(flag)
+			if err != nil {
+				continue
+			}
+			desiredFlags = append(desiredFlags, formatFlag(flagType, data, content))
+		}
+		if !equalStringSets(liveFlags, desiredFlags) {
+			add("flags", liveFlags, desiredFlags)
+		}
+	}
+
+	// Compare hints
+	hints, err := getCTFdChallengeHints(live.ID, client)
+	if err != nil {
+		log.Printf("Error getting hints for challenge %d: %s\n", live.ID, err)
+	} else if differing, removed := diffCTFdChallengeHints(hints, challenge.Challenge.Hints); len(differing) > 0 || len(removed) > 0 {
+		add("hints", convertCTFdChallengeHints(hints), challenge.Challenge.Hints)
+	}
+
+	// Compare tags
+	tags, err := client.GetChallengeTags(live.ID)
+	if err != nil {
+		log.Printf("Error getting tags for challenge %d: %s\n", live.ID, err)
+	} else {
+		liveTags := []string{}
+		for _, tag := range tags {
+			liveTags = append(liveTags, tag.Value)
+		}
+		desiredTags := getChallengeTags(challenge, mappingMap)
+		if !equalStringSets(liveTags, desiredTags) {
+			add("tags", liveTags, desiredTags)
+		}
+	}
+
+	// Compare topics
+	topics, err := client.GetChallengeTopics(live.ID)
+	if err != nil {
+		log.Printf("Error getting topics for challenge %d: %s\n", live.ID, err)
+	} else {
+		liveTopics := []string{}
+		for _, topic := range topics {
+			liveTopics = append(liveTopics, topic.Value)
+		}
+		desiredTopics := getChallengeTopics(challenge, mappingMap)
+		if !equalStringSets(liveTopics, desiredTopics) {
+			add("topics", liveTopics, desiredTopics)
+		}
+	}
+
+	// Compare files by name
+	liveFiles := []string{}
+	for _, file := range ctfdFiles {
+		liveFiles = append(liveFiles, path.Base(file.Location))
+	}
+	desiredFiles, err := getChallengeFileNames(challenge)
+	if err != nil {
+		log.Printf("Error getting files for challenge %s: %s\n", challenge.Challenge.Slug, err)
+	} else if !equalStringSets(liveFiles, desiredFiles) {
+		add("files", liveFiles, desiredFiles)
+	}
+
+	return diffs, nil
+}
+
+// Get the fields where the live CTFd page differs from the page config
+func diffCTFdPage(page *Page, live *ctfd.Page) []FieldDiff {
+	diffs := []FieldDiff{}
+	add := func(field string, current any, desired any) {
+		diffs = append(diffs, FieldDiff{Field: field, Current: current, Desired: desired})
+	}
+
+	if live.Title != page.Title {
+		add("title", live.Title, page.Title)
+	}
+	if live.Route != page.Route {
+		add("route", live.Route, page.Route)
+	}
+	if live.AuthRequired != page.AuthRequired {
+		add("auth_required", live.AuthRequired, page.AuthRequired)
+	}
+	if live.Draft != page.Draft {
+		add("draft", live.Draft, page.Draft)
+	}
+	if live.Format != page.Format {
+		add("format", live.Format, page.Format)
+	}
+	if live.Hidden != !page.Enabled {
+		add("enabled", !live.Hidden, page.Enabled)
+	}
+	if live.Content != nil && *live.Content != page.Content {
+		add("content", *live.Content, page.Content)
+	}
+
+	return diffs
+}
+
+// Format a flag for comparison, as "type(data): content"
+func formatFlag(flagType string, data string, content string) string {
+	return flagType + "(" + data + "): " + content
+}
+
+func diffFieldNames(diffs []FieldDiff) []string {
+	fields := []string{}
+	for _, diff := range diffs {
+		fields = append(fields, diff.Field)
+	}
+	return fields
+}
+
+func equalIntPointer(a *int, b int) bool {
+	if a == nil {
+		return b == 0
+	}
+	return *a == b
+}
+
+func intPointerValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func stringPointerValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func equalStringSets(a []string, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func equalIntSets(a []int, b []int) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Annotation selecting how drift between CTFd and a challenge configmap is handled
+const driftPolicyAnnotation = "challenges.ctfpilot.com/drift-policy"
+
+const (
+	DriftPolicyRevert = "revert" // Re-apply the configmap, overwriting the changes made in CTFd
+	DriftPolicyAdopt  = "adopt"  // Keep the changes made in CTFd, by storing them as overrides of the configmap
+	DriftPolicyIgnore = "ignore" // Report the drift, but leave CTFd as is
+)
+
+// Fields that can be adopted into the override layer. See RenderedChallenge.
+var adoptableFields = map[string]bool{
+	"name":         true,
+	"category":     true,
+	"description":  true,
+	"attribution":  true,
+	"state":        true,
+	"points":       true,
+	"decay":        true,
+	"min_points":   true,
+	"max_attempts": true,
+	"connection":   true,
+}
+
+type ChallengeDrift struct {
+	ConfigMap string      `json:"configmap"`
+	Slug      string      `json:"slug"`
+	ID        int         `json:"id"`
+	Policy    string      `json:"policy"`
+	Diffs     []FieldDiff `json:"diffs"`
+}
+
+func isValidDriftPolicy(policy string) bool {
+	return policy == DriftPolicyRevert || policy == DriftPolicyAdopt || policy == DriftPolicyIgnore
+}
+
+// Get the drift policy of a challenge configmap, falling back to the DRIFT_POLICY default
+func getChallengeDriftPolicy(configMap *corev1.ConfigMap) string {
+	if policy, ok := configMap.Annotations[driftPolicyAnnotation]; ok {
+		if isValidDriftPolicy(policy) {
+			return policy
+		}
+		log.Printf("Invalid drift policy %q on configmap %s, using default\n", policy, configMap.Name)
+	}
+
+	return getDriftPolicy()
+}
+
+// Compare every uploaded challenge in CTFd with the challenge rendered from its configmap
+func detectDrift() ([]ChallengeDrift, error) {
+	client, err := getCTFdClient()
+	if err != nil {
+		log.Printf("Error getting CTFd client: %s\n", err)
+		return nil, err
+	}
+
+	challengeConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return nil, err
+	}
+
+	mappingMap, err := getMappingMap(getNamespace())
+	if err != nil {
+		log.Printf("Error getting mapping map: %s\n", err)
+		return nil, err
+	}
+
+	drifts := []ChallengeDrift{}
+	for i := range challengeConfigMaps {
+		drift, err := detectChallengeDrift(&challengeConfigMaps[i], client, mappingMap)
+		if err != nil {
+			log.Printf("Error detecting drift of configmap %s: %s\n", challengeConfigMaps[i].Name, err)
+			continue
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+
+	return drifts, nil
+}
+
+// Detect the drift of a single challenge configmap. Returns nil if the challenge has not drifted, or is not in CTFd.
+func detectChallengeDrift(configMap *corev1.ConfigMap, client *ctfd.Client, mappingMap MappingMap) (*ChallengeDrift, error) {
+	challengeConfig, err := extractChallengeConfigMap(configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadedChallengeID, _ := getUploadedCTFdChallenge(challengeConfig.Challenge.Slug)
+	id, err := strconv.Atoi(uploadedChallengeID)
+	if err != nil || id == 0 {
+		return nil, nil
+	}
+
+	live, err := client.GetChallenge(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	diffs, err := diffCTFdChallenge(challengeConfig, live, client, mappingMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+
+	return &ChallengeDrift{
+		ConfigMap: configMap.Name,
+		Slug:      challengeConfig.Challenge.Slug,
+		ID:        id,
+		Policy:    getChallengeDriftPolicy(configMap),
+		Diffs:     diffs,
+	}, nil
+}
+
+// Store the live CTFd values of the adoptable drifted fields as overrides of the challenge.
+// Returns the diffs that could not be adopted.
+func adoptChallengeDrift(slug string, diffs []FieldDiff) ([]FieldDiff, error) {
+	overrides, err := getChallengeOverrides(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := []FieldDiff{}
+	adopted := 0
+	for _, diff := range diffs {
+		if !adoptableFields[diff.Field] {
+			remaining = append(remaining, diff)
+			continue
+		}
+		overrides[diff.Field] = diff.Current
+		adopted++
+	}
+
+	if adopted == 0 {
+		return remaining, nil
+	}
+
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := overrideStore.Set(slug, string(data)); err != nil {
+		log.Printf("Error storing overrides for challenge %s: %s\n", slug, err)
+		return nil, err
+	}
+	log.Printf("Adopted %d drifted fields of challenge %s from CTFd\n", adopted, slug)
+
+	return remaining, nil
+}
+
+// Get the stored overrides of a challenge as JSON, or an empty string if there are none.
+// A missing challenge-overrides configmap means no overrides, so the manager keeps working until it is created.
+func getChallengeOverridesData(slug string) (string, error) {
+	data, _, err := overrideStore.Get(slug)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error getting overrides for challenge %s: %s\n", slug, err)
+		return "", err
+	}
+	return data, nil
+}
+
+// Get the overrides adopted from CTFd for a challenge
+func getChallengeOverrides(slug string) (map[string]any, error) {
+	overrides := make(map[string]any)
+
+	data, err := getChallengeOverridesData(slug)
+	if err != nil {
+		return nil, err
+	}
+	if data == "" {
+		return overrides, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// Remove the overrides adopted from CTFd for a challenge, so the configmap applies again
+func clearChallengeOverrides(slug string) error {
+	err := overrideStore.Delete(slug)
+	if apierrors.IsNotFound(err) {
+		// Nothing has been adopted yet
+		return nil
+	}
+	return err
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
-	"log"
+	"time"
 )
 
 func getPassword() string {
@@ -33,31 +36,22 @@ func getNamespace() string {
 	return namespace
 }
 
+// Optional, as only GitHub repositories need a token, and public repositories can be accessed without one
 func getGithubToken() string {
 	// Load data from env
-	github_token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
-	if github_token == "" {
-		log.Fatal("GITHUB_TOKEN environment variable is not set")
-	}
-	return github_token
+	return strings.TrimSpace(os.Getenv("GITHUB_TOKEN"))
 }
 
+// Optional, only used to check access to the GitHub API
 func getGithubUser() string {
 	// Load data from env
-	github_user := strings.TrimSpace(os.Getenv("GITHUB_USER"))
-	if github_user == "" {
-		log.Fatal("GITHUB_USER environment variable is not set")
-	}
-	return github_user
+	return strings.TrimSpace(os.Getenv("GITHUB_USER"))
 }
 
+// Default repository of challenges without a repository. Optional, if all challenges set their repository
 func getGithubRepo() string {
 	// Load data from env
-	github_repo := strings.TrimSpace(os.Getenv("GITHUB_REPO"))
-	if github_repo == "" {
-		log.Fatal("GITHUB_REPO environment variable is not set")
-	}
-	return github_repo
+	return strings.TrimSpace(os.Getenv("GITHUB_REPO"))
 }
 
 func getGithubBranch() string {
@@ -77,3 +71,215 @@ func getCTFdURL() string {
 	}
 	return ctfd_url
 }
+
+func getReconcileInterval() time.Duration {
+	return getDurationEnv("RECONCILE_INTERVAL", 10*time.Minute)
+}
+
+func getWatcherResyncInterval() time.Duration {
+	return getDurationEnv("WATCHER_RESYNC_INTERVAL", 10*time.Minute)
+}
+
+func getLeaderElectionEnabled() bool {
+	// Load data from env
+	enabled := strings.TrimSpace(os.Getenv("LEADER_ELECTION"))
+	if enabled == "" {
+		return false
+	}
+	value, err := strconv.ParseBool(enabled)
+	if err != nil {
+		log.Printf("Invalid LEADER_ELECTION %q, defaulting to false\n", enabled)
+		return false
+	}
+	return value
+}
+
+func getLeaderElectionLease() string {
+	// Load data from env
+	lease := strings.TrimSpace(os.Getenv("LEADER_ELECTION_LEASE"))
+	if lease == "" {
+		return "ctfd-manager"
+	}
+	return lease
+}
+
+func getPodName() string {
+	// Load data from env
+	podName := strings.TrimSpace(os.Getenv("POD_NAME"))
+	if podName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatal("POD_NAME environment variable is not set, and hostname is unavailable")
+		}
+		return hostname
+	}
+	return podName
+}
+
+func getStateBackend() string {
+	// Load data from env
+	backend := strings.TrimSpace(os.Getenv("STATE_BACKEND"))
+	if backend == "" {
+		return "configmap"
+	}
+	return backend
+}
+
+func getStateMigrateFrom() string {
+	// Load data from env
+	return strings.TrimSpace(os.Getenv("STATE_MIGRATE_FROM"))
+}
+
+func getStateShards() int {
+	// Load data from env
+	shards := strings.TrimSpace(os.Getenv("STATE_SHARDS"))
+	if shards == "" {
+		return 8
+	}
+	value, err := strconv.Atoi(shards)
+	if err != nil || value < 1 {
+		log.Printf("Invalid STATE_SHARDS %q, defaulting to 8\n", shards)
+		return 8
+	}
+	return value
+}
+
+func getStateFilePath() string {
+	// Load data from env
+	path := strings.TrimSpace(os.Getenv("STATE_FILE_PATH"))
+	if path == "" {
+		return "/data/ctfd-manager.db"
+	}
+	return path
+}
+
+func getGitCacheDir() string {
+	// Load data from env
+	dir := strings.TrimSpace(os.Getenv("GIT_CACHE_DIR"))
+	if dir == "" {
+		return filepath.Join(os.TempDir(), "ctfd-manager-git")
+	}
+	return dir
+}
+
+// Directory local file:// repositories must be in. Local repositories are disabled if not set.
+func getLocalRepositoryRoot() string {
+	// Load data from env
+	return strings.TrimSpace(os.Getenv("LOCAL_REPOSITORY_ROOT"))
+}
+
+func getSnapshotCacheDir() string {
+	// Load data from env
+	dir := strings.TrimSpace(os.Getenv("SNAPSHOT_CACHE_DIR"))
+	if dir == "" {
+		return filepath.Join(os.TempDir(), "ctfd-manager-snapshots")
+	}
+	return dir
+}
+
+// Maximum size of the snapshot cache in bytes. 0 disables the cache.
+func getSnapshotCacheSize() int64 {
+	// Load data from env
+	size := strings.TrimSpace(os.Getenv("SNAPSHOT_CACHE_SIZE_MB"))
+	if size == "" {
+		return 1024 * 1024 * 1024
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value < 0 {
+		log.Printf("Invalid SNAPSHOT_CACHE_SIZE_MB %q, defaulting to 1024\n", size)
+		return 1024 * 1024 * 1024
+	}
+	return value * 1024 * 1024
+}
+
+func getS3Endpoint() string {
+	// Load data from env
+	endpoint := strings.TrimSpace(os.Getenv("S3_ENDPOINT"))
+	if endpoint == "" {
+		return "s3.amazonaws.com"
+	}
+	return endpoint
+}
+
+func getS3Region() string {
+	// Load data from env
+	return strings.TrimSpace(os.Getenv("S3_REGION"))
+}
+
+func getS3AccessKeyID() string {
+	// Load data from env
+	return strings.TrimSpace(os.Getenv("S3_ACCESS_KEY_ID"))
+}
+
+func getS3SecretAccessKey() string {
+	// Load data from env
+	return strings.TrimSpace(os.Getenv("S3_SECRET_ACCESS_KEY"))
+}
+
+func getS3Insecure() bool {
+	// Load data from env
+	insecure := strings.TrimSpace(os.Getenv("S3_INSECURE"))
+	if insecure == "" {
+		return false
+	}
+	value, err := strconv.ParseBool(insecure)
+	if err != nil {
+		log.Printf("Invalid S3_INSECURE %q, defaulting to false\n", insecure)
+		return false
+	}
+	return value
+}
+
+func getSyncMaxAttempts() int {
+	// Load data from env
+	maxAttempts := strings.TrimSpace(os.Getenv("SYNC_MAX_ATTEMPTS"))
+	if maxAttempts == "" {
+		return 10
+	}
+	value, err := strconv.Atoi(maxAttempts)
+	if err != nil || value < 1 {
+		log.Printf("Invalid SYNC_MAX_ATTEMPTS %q, defaulting to 10\n", maxAttempts)
+		return 10
+	}
+	return value
+}
+
+func getDriftPolicy() string {
+	// Load data from env
+	policy := strings.TrimSpace(os.Getenv("DRIFT_POLICY"))
+	if policy == "" {
+		return DriftPolicyRevert
+	}
+	if !isValidDriftPolicy(policy) {
+		log.Printf("Invalid DRIFT_POLICY %q, defaulting to %s\n", policy, DriftPolicyRevert)
+		return DriftPolicyRevert
+	}
+	return policy
+}
+
+func getOrphanPolicy() string {
+	// Load data from env
+	policy := strings.TrimSpace(os.Getenv("ORPHAN_POLICY"))
+	if policy == "" {
+		return OrphanPolicyHide
+	}
+	if !isValidOrphanPolicy(policy) {
+		log.Printf("Invalid ORPHAN_POLICY %q, defaulting to %s\n", policy, OrphanPolicyHide)
+		return OrphanPolicyHide
+	}
+	return policy
+}
+
+// Load a Go duration from env, falling back to the default if unset or invalid
+func getDurationEnv(name string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Printf("Invalid %s %q, defaulting to %s\n", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
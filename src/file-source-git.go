@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a fetched branch or tag is reused, before it is fetched again
+const gitRefCacheDuration = time.Minute
+
+// Locks of the git cache directories, so each repository is only fetched by one sync at a time
+var gitRepositoryLocks sync.Map
+
+// File source backed by a git repository over HTTPS or SSH, using the git binary.
+// Refs are fetched into a bare repository in GIT_CACHE_DIR, and files are read from the fetched commits.
+type gitFileSource struct {
+	url string
+	dir string
+}
+
+// Commits of the fetched refs, by cache directory and ref
+var gitRefCache = struct {
+	sync.Mutex
+	refs map[string]gitCachedRef
+}{refs: map[string]gitCachedRef{}}
+
+type gitCachedRef struct {
+	commit    string
+	fetchedAt time.Time
+}
+
+func newGitFileSource(url string) (*gitFileSource, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "ssh://") {
+		return nil, fmt.Errorf("unsupported git repository %s, only https:// and ssh:// are supported", url)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git repository %s requires the git binary: %w", url, err)
+	}
+
+	// Each repository gets its own cache directory
+	hash := sha256.Sum256([]byte(url))
+	dir := filepath.Join(getGitCacheDir(), hex.EncodeToString(hash[:8]))
+
+	return &gitFileSource{url: url, dir: dir}, nil
+}
+
+// Run a git command in the cache directory, returning its output
+func (source *gitFileSource) git(timeout time.Duration, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", source.dir}, args...)...)
+	// Only allow the supported transports, also for redirects
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=https:ssh")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// Resolve the ref to a commit, fetching it from the remote if needed
+func (source *gitFileSource) resolve(ref string) (string, error) {
+	// The ref comes from the configmap, so make sure it can not be read as an option
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %q", ref)
+	}
+	if !commitPattern.MatchString(ref) {
+		if _, err := source.git(time.Minute, "check-ref-format", "--allow-onelevel", ref); err != nil {
+			return "", fmt.Errorf("invalid git ref %q", ref)
+		}
+	}
+
+	lock, _ := gitRepositoryLocks.LoadOrStore(source.dir, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if _, err := os.Stat(source.dir); os.IsNotExist(err) {
+		if _, err := source.git(time.Minute, "init", "--bare", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	// Commits are immutable, so they only need to be fetched once
+	if commitPattern.MatchString(ref) {
+		if _, err := source.git(time.Minute, "cat-file", "-e", ref+"^{commit}"); err == nil {
+			return ref, nil
+		}
+	}
+
+	key := source.dir + "@" + ref
+	gitRefCache.Lock()
+	cached, ok := gitRefCache.refs[key]
+	gitRefCache.Unlock()
+	if ok && time.Since(cached.fetchedAt) < gitRefCacheDuration {
+		return cached.commit, nil
+	}
+
+	if _, err := source.git(10*time.Minute, "fetch", "--quiet", "--no-tags", "--depth", "1", "--end-of-options", source.url, ref); err != nil {
+		return "", err
+	}
+	output, err := source.git(time.Minute, "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(output))
+
+	// Keep a ref to the commit, so its objects are not pruned by garbage collection
+	if _, err := source.git(time.Minute, "update-ref", "refs/fetched/"+commit, commit); err != nil {
+		return "", err
+	}
+
+	gitRefCache.Lock()
+	gitRefCache.refs[key] = gitCachedRef{commit: commit, fetchedAt: time.Now()}
+	gitRefCache.Unlock()
+
+	return commit, nil
+}
+
+func (source *gitFileSource) ListFiles(ref string, dir string) ([]SourceFile, error) {
+	commit, err := source.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"ls-tree", "-z", "-l", commit}
+	if dir := cleanSourcePath(dir); dir != "" {
+		args = append(args, "--", dir+"/")
+	}
+
+	// Entries are "<mode> <type> <sha> <size>\t<path>", separated by NUL
+	output, err := source.git(time.Minute, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []SourceFile{}
+	for _, entry := range strings.Split(string(output), "\x00") {
+		meta, filePath, found := strings.Cut(entry, "\t")
+		fields := strings.Fields(meta)
+		if !found || len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+
+		size, _ := strconv.ParseInt(fields[3], 10, 64)
+		files = append(files, SourceFile{
+			Name: path.Base(filePath),
+			Path: filePath,
+			Size: size,
+			SHA:  fields[2],
+		})
+	}
+
+	return files, nil
+}
+
+func (source *gitFileSource) ReadFile(ref string, filePath string) ([]byte, error) {
+	commit, err := source.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return source.git(5*time.Minute, "cat-file", "blob", commit+":"+cleanSourcePath(filePath))
+}
+
+func (source *gitFileSource) FileVersion(ref string, filePath string) (string, error) {
+	commit, err := source.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := source.git(time.Minute, "rev-parse", commit+":"+cleanSourcePath(filePath))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (source *gitFileSource) SupportsRefs() bool {
+	return true
+}
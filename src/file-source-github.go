@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// File source backed by a GitHub repository.
+// Files are read from a snapshot of the repository at the commit of the ref, downloaded as a single tarball,
+// falling back to the GitHub Contents API if no snapshot is available.
+type githubFileSource struct {
+	repo string // owner/repo
+}
+
+func newGithubFileSource(repository string) (*githubFileSource, error) {
+	if githubClient == nil {
+		return nil, errors.New("GitHub client not initialized")
+	}
+
+	// Accept repository URLs as well as owner/repo
+	repo := strings.TrimPrefix(repository, "https://")
+	repo = strings.TrimPrefix(repo, "github.com/")
+	repo = strings.TrimSuffix(repo, ".git")
+	repo = strings.Trim(repo, "/")
+
+	if owner, name := splitRepo(repo); owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid GitHub repository %q, expected owner/repo", repository)
+	}
+
+	return &githubFileSource{repo: repo}, nil
+}
+
+// Resolve the ref to a commit. Branches and tags are resolved at most once per gitRefCacheDuration.
+func (source *githubFileSource) resolve(ref string) (string, error) {
+	if commitPattern.MatchString(ref) {
+		return ref, nil
+	}
+
+	key := "github:" + source.repo + "@" + ref
+	gitRefCache.Lock()
+	cached, ok := gitRefCache.refs[key]
+	gitRefCache.Unlock()
+	if ok && time.Since(cached.fetchedAt) < gitRefCacheDuration {
+		return cached.commit, nil
+	}
+
+	commit, err := getGithubCommitSHA(source.repo, ref)
+	if err != nil {
+		return "", err
+	}
+
+	gitRefCache.Lock()
+	gitRefCache.refs[key] = gitCachedRef{commit: commit, fetchedAt: time.Now()}
+	gitRefCache.Unlock()
+
+	return commit, nil
+}
+
+// Run fn on the snapshot of the repository at the ref.
+// Returns false if no snapshot is available, in which case the GitHub Contents API should be used instead.
+func (source *githubFileSource) withSnapshot(ref string, fn func(snapshot *localFileSource) error) (bool, error) {
+	cache := getSnapshotCache()
+	if cache == nil {
+		return false, nil
+	}
+
+	commit, err := source.resolve(ref)
+	if err != nil {
+		log.Printf("Error resolving %s of %s, fetching files individually: %s\n", ref, source.repo, err)
+		return false, nil
+	}
+
+	key := strings.ReplaceAll(source.repo, "/", "-") + "-" + commit
+	err = cache.use(key, func(dir string) error {
+		log.Printf("Downloading snapshot of %s at %s\n", source.repo, commit)
+		tarball, err := downloadGithubTarball(source.repo, commit)
+		if err != nil {
+			return err
+		}
+		defer tarball.Close()
+		return extractTarball(tarball, dir, cache.maxSize)
+	}, func(dir string) error {
+		return fn(&localFileSource{root: dir})
+	})
+	if errors.Is(err, errSnapshotUnavailable) {
+		log.Printf("Error getting snapshot of %s, fetching files individually: %s\n", source.repo, err)
+		return false, nil
+	}
+
+	return true, err
+}
+
+func (source *githubFileSource) ListFiles(ref string, dir string) ([]SourceFile, error) {
+	var files []SourceFile
+	if ok, err := source.withSnapshot(ref, func(snapshot *localFileSource) (err error) {
+		files, err = snapshot.ListFiles(ref, dir)
+		return err
+	}); ok {
+		return files, err
+	}
+
+	contents, err := getGithubDirContents(source.repo, ref, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files = []SourceFile{}
+	for _, content := range contents {
+		if content.GetType() != "file" {
+			continue
+		}
+		files = append(files, SourceFile{
+			Name: content.GetName(),
+			Path: content.GetPath(),
+			Size: int64(content.GetSize()),
+			SHA:  content.GetSHA(),
+		})
+	}
+
+	return files, nil
+}
+
+func (source *githubFileSource) ReadFile(ref string, filePath string) ([]byte, error) {
+	var data []byte
+	if ok, err := source.withSnapshot(ref, func(snapshot *localFileSource) (err error) {
+		data, err = snapshot.ReadFile(ref, filePath)
+		return err
+	}); ok {
+		return data, err
+	}
+
+	content, err := getGithubFileBytes(source.repo, ref, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(*content), nil
+}
+
+func (source *githubFileSource) FileVersion(ref string, filePath string) (string, error) {
+	// The snapshot versions are git blob SHAs, matching the versions of the GitHub Contents API
+	var version string
+	if ok, err := source.withSnapshot(ref, func(snapshot *localFileSource) (err error) {
+		version, err = snapshot.FileVersion(ref, filePath)
+		return err
+	}); ok {
+		return version, err
+	}
+
+	return getGithubFileSHA(source.repo, ref, filePath)
+}
+
+func (source *githubFileSource) SupportsRefs() bool {
+	return true
+}
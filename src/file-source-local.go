@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File source backed by a local directory, such as a volume kept up to date by a git-sync sidecar.
+// The directory always serves its current files, so refs are ignored.
+type localFileSource struct {
+	root string
+}
+
+// Create a file source for a file:// repository, which must be within LOCAL_REPOSITORY_ROOT
+func newLocalRepositoryFileSource(dir string) (*localFileSource, error) {
+	allowedRoot := getLocalRepositoryRoot()
+	if allowedRoot == "" {
+		return nil, fmt.Errorf("local repository %s is not allowed, as LOCAL_REPOSITORY_ROOT is not set", dir)
+	}
+
+	// Check the path before accessing it, and again once symlinks are resolved
+	if !filepath.IsAbs(dir) || !isWithinDir(filepath.Clean(allowedRoot), filepath.Clean(dir)) {
+		return nil, fmt.Errorf("local repository %s is not within LOCAL_REPOSITORY_ROOT", dir)
+	}
+
+	allowedRoot, err := filepath.EvalSymlinks(allowedRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error opening LOCAL_REPOSITORY_ROOT: %w", err)
+	}
+	root, err := filepath.EvalSymlinks(filepath.Clean(dir))
+	if err != nil {
+		return nil, fmt.Errorf("error opening local repository %s: %w", dir, err)
+	}
+	if !isWithinDir(allowedRoot, root) {
+		return nil, fmt.Errorf("local repository %s is not within LOCAL_REPOSITORY_ROOT", dir)
+	}
+
+	return newLocalFileSource(root)
+}
+
+func newLocalFileSource(root string) (*localFileSource, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("error opening local repository %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local repository %s is not a directory", root)
+	}
+
+	return &localFileSource{root: root}, nil
+}
+
+// Check if the path is the directory or within it
+func isWithinDir(dir string, filePath string) bool {
+	rel, err := filepath.Rel(dir, filePath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Get the path of a file on disk, within the root of the source.
+// Symlinks are resolved, so they can not point outside the root.
+func (source *localFileSource) resolve(filePath string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(source.root, filepath.FromSlash(cleanSourcePath(filePath))))
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.EvalSymlinks(source.root)
+	if err != nil {
+		return "", err
+	}
+	if !isWithinDir(root, resolved) {
+		return "", errors.New(filePath + " is outside the repository")
+	}
+
+	return resolved, nil
+}
+
+func (source *localFileSource) ListFiles(ref string, dir string) ([]SourceFile, error) {
+	dirPath, err := source.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []SourceFile{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		filePath := path.Join(cleanSourcePath(dir), entry.Name())
+		data, err := os.ReadFile(filepath.Join(dirPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, SourceFile{
+			Name: entry.Name(),
+			Path: filePath,
+			Size: int64(len(data)),
+			SHA:  gitBlobSHA(data),
+		})
+	}
+
+	return files, nil
+}
+
+func (source *localFileSource) ReadFile(ref string, filePath string) ([]byte, error) {
+	resolved, err := source.resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.New(filePath + " is not a file")
+	}
+
+	return os.ReadFile(resolved)
+}
+
+func (source *localFileSource) FileVersion(ref string, filePath string) (string, error) {
+	data, err := source.ReadFile(ref, filePath)
+	if err != nil {
+		return "", err
+	}
+	return gitBlobSHA(data), nil
+}
+
+func (source *localFileSource) SupportsRefs() bool {
+	return false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// File source backed by a prefix in an S3-compatible bucket.
+// The bucket always serves its current objects, so refs are ignored.
+type s3FileSource struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// Create a file source from a "bucket/prefix" location, using the S3 environment variables
+func newS3FileSource(location string) (*s3FileSource, error) {
+	bucket, prefix, _ := strings.Cut(location, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 repository %q, expected s3://bucket/prefix", "s3://"+location)
+	}
+
+	// Use static credentials if set, otherwise the standard AWS environment variables or the IAM role
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.IAM{},
+	})
+	if getS3AccessKeyID() != "" {
+		creds = credentials.NewStaticV4(getS3AccessKeyID(), getS3SecretAccessKey(), "")
+	}
+
+	client, err := minio.New(getS3Endpoint(), &minio.Options{
+		Creds:  creds,
+		Secure: !getS3Insecure(),
+		Region: getS3Region(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating S3 client: %w", err)
+	}
+
+	return &s3FileSource{
+		client: client,
+		bucket: bucket,
+		prefix: cleanSourcePath(prefix),
+	}, nil
+}
+
+// Get the object key of a file in the source
+func (source *s3FileSource) key(filePath string) string {
+	return path.Join(source.prefix, cleanSourcePath(filePath))
+}
+
+func (source *s3FileSource) ListFiles(ref string, dir string) ([]SourceFile, error) {
+	prefix := source.key(dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	files := []SourceFile{}
+	for object := range source.client.ListObjects(context.Background(), source.bucket, minio.ListObjectsOptions{
+		Prefix: prefix,
+	}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		// Skip subdirectories
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+
+		files = append(files, SourceFile{
+			Name: path.Base(object.Key),
+			Path: strings.TrimPrefix(strings.TrimPrefix(object.Key, source.prefix), "/"),
+			Size: object.Size,
+			SHA:  object.ETag,
+		})
+	}
+
+	return files, nil
+}
+
+func (source *s3FileSource) ReadFile(ref string, filePath string) ([]byte, error) {
+	object, err := source.client.GetObject(context.Background(), source.bucket, source.key(filePath), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return io.ReadAll(object)
+}
+
+func (source *s3FileSource) FileVersion(ref string, filePath string) (string, error) {
+	info, err := source.client.StatObject(context.Background(), source.bucket, source.key(filePath), minio.StatObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+func (source *s3FileSource) SupportsRefs() bool {
+	return false
+}
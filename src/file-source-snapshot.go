@@ -0,0 +1,285 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Returned when no snapshot can be made, in which case files should be fetched individually
+var errSnapshotUnavailable = errors.New("snapshot unavailable")
+
+// How long snapshots that failed to download are not retried, so each sync doesn't download them again
+const snapshotFailureCacheDuration = 10 * time.Minute
+
+// Cache of extracted repository snapshots in SNAPSHOT_CACHE_DIR, keyed by commit.
+// The least recently used snapshots are evicted once the cache grows beyond SNAPSHOT_CACHE_SIZE_MB.
+type snapshotCache struct {
+	// Held for reading while a snapshot is in use, and for writing while snapshots are added or evicted
+	sync.RWMutex
+	dir       string
+	maxSize   int64
+	size      int64
+	snapshots map[string]*repositorySnapshot
+	// Locks of the snapshots being downloaded, so each snapshot is only downloaded once
+	downloads sync.Map
+	// Time of the last failed download of each snapshot
+	failures sync.Map
+}
+
+type repositorySnapshot struct {
+	size     int64
+	lastUsed atomic.Int64 // Unix nanoseconds
+}
+
+var snapshotCacheOnce sync.Once
+var snapshotCacheInstance *snapshotCache
+
+// Get the snapshot cache, or nil if it is disabled
+func getSnapshotCache() *snapshotCache {
+	snapshotCacheOnce.Do(func() {
+		maxSize := getSnapshotCacheSize()
+		if maxSize == 0 {
+			log.Println("Snapshot cache disabled, files are fetched individually")
+			return
+		}
+
+		cache, err := newSnapshotCache(getSnapshotCacheDir(), maxSize)
+		if err != nil {
+			log.Printf("Error initializing snapshot cache, files are fetched individually: %s\n", err)
+			return
+		}
+		snapshotCacheInstance = cache
+	})
+	return snapshotCacheInstance
+}
+
+// Create the snapshot cache, picking up the snapshots already in the directory
+func newSnapshotCache(dir string, maxSize int64) (*snapshotCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &snapshotCache{
+		dir:       dir,
+		maxSize:   maxSize,
+		snapshots: map[string]*repositorySnapshot{},
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+
+		// Remove downloads that were interrupted by a restart
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			os.RemoveAll(entryPath)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		size, err := dirSize(entryPath)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot := &repositorySnapshot{size: size}
+		snapshot.lastUsed.Store(info.ModTime().UnixNano())
+		cache.snapshots[entry.Name()] = snapshot
+		cache.size += size
+	}
+	cache.evict(0)
+
+	return cache, nil
+}
+
+// Run fn with the directory of the snapshot, downloading the snapshot if it is not cached.
+// The snapshot is not evicted while fn runs.
+func (cache *snapshotCache) use(key string, download func(dir string) error, fn func(dir string) error) error {
+	if ok, err := cache.useCached(key, fn); ok {
+		return err
+	}
+
+	lock, _ := cache.downloads.LoadOrStore(key, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// The snapshot may have been downloaded while waiting for the lock
+	if ok, err := cache.useCached(key, fn); ok {
+		return err
+	}
+
+	// Skip snapshots that recently failed, such as snapshots larger than the cache
+	if failedAt, ok := cache.failures.Load(key); ok && time.Since(failedAt.(time.Time)) < snapshotFailureCacheDuration {
+		return fmt.Errorf("%w: snapshot %s failed less than %s ago", errSnapshotUnavailable, key, snapshotFailureCacheDuration)
+	}
+
+	if err := cache.add(key, download); err != nil {
+		cache.failures.Store(key, time.Now())
+		return fmt.Errorf("%w: %w", errSnapshotUnavailable, err)
+	}
+	cache.failures.Delete(key)
+
+	if ok, err := cache.useCached(key, fn); ok {
+		return err
+	}
+	return fmt.Errorf("%w: snapshot %s was evicted", errSnapshotUnavailable, key)
+}
+
+// Run fn with the directory of the snapshot, if it is cached
+func (cache *snapshotCache) useCached(key string, fn func(dir string) error) (bool, error) {
+	cache.RLock()
+	defer cache.RUnlock()
+
+	snapshot, ok := cache.snapshots[key]
+	if !ok {
+		return false, nil
+	}
+	snapshot.lastUsed.Store(time.Now().UnixNano())
+
+	return true, fn(filepath.Join(cache.dir, key))
+}
+
+// Download a snapshot into the cache, evicting the least recently used snapshots to make room for it
+func (cache *snapshotCache) add(key string, download func(dir string) error) error {
+	// Download into a temporary directory, so incomplete snapshots are never used
+	tmp, err := os.MkdirTemp(cache.dir, ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := download(tmp); err != nil {
+		return err
+	}
+	size, err := dirSize(tmp)
+	if err != nil {
+		return err
+	}
+	if size > cache.maxSize {
+		return fmt.Errorf("snapshot %s is %d bytes, larger than the cache", key, size)
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	cache.evict(size)
+	if err := os.Rename(tmp, filepath.Join(cache.dir, key)); err != nil {
+		return err
+	}
+
+	snapshot := &repositorySnapshot{size: size}
+	snapshot.lastUsed.Store(time.Now().UnixNano())
+	cache.snapshots[key] = snapshot
+	cache.size += size
+
+	log.Printf("Cached snapshot %s (%d bytes, cache is %d of %d bytes)\n", key, size, cache.size, cache.maxSize)
+	return nil
+}
+
+// Evict the least recently used snapshots, until there is room for the given number of bytes.
+// Must be called with the write lock held.
+func (cache *snapshotCache) evict(room int64) {
+	for cache.size+room > cache.maxSize && len(cache.snapshots) > 0 {
+		oldestKey := ""
+		oldest := int64(0)
+		for key, snapshot := range cache.snapshots {
+			if lastUsed := snapshot.lastUsed.Load(); oldestKey == "" || lastUsed < oldest {
+				oldestKey = key
+				oldest = lastUsed
+			}
+		}
+
+		if err := os.RemoveAll(filepath.Join(cache.dir, oldestKey)); err != nil {
+			log.Printf("Error evicting snapshot %s: %s\n", oldestKey, err)
+		}
+		cache.size -= cache.snapshots[oldestKey].size
+		delete(cache.snapshots, oldestKey)
+		log.Printf("Evicted snapshot %s\n", oldestKey)
+	}
+}
+
+// Extract a gzipped repository tarball into the directory, stripping the top-level directory of the archive.
+// Only regular files are extracted, and extraction stops once more than maxSize bytes are extracted.
+func extractTarball(r io.Reader, dir string, maxSize int64) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	size := int64(0)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Strip the top-level directory, and keep the file within the directory
+		_, name, _ := strings.Cut(header.Name, "/")
+		name = cleanSourcePath(name)
+		if name == "" {
+			continue
+		}
+
+		size += header.Size
+		if size > maxSize {
+			return fmt.Errorf("snapshot is larger than %d bytes", maxSize)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, archive)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Get the total size of the files in a directory
+func dirSize(dir string) (int64, error) {
+	size := int64(0)
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
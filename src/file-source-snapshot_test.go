@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+}
+
+// Build a gzipped tarball of the entries
+func buildTarball(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	gz := gzip.NewWriter(buffer)
+	archive := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Mode:     0o644,
+			Size:     int64(len(entry.content)),
+		}
+		if entry.typeflag == tar.TypeSymlink || entry.typeflag == tar.TypeLink {
+			header.Linkname = entry.content
+			header.Size = 0
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := archive.Write([]byte(entry.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer
+}
+
+// Get the contents of the regular files in the directory, keyed by slash separated path
+func readDirFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestExtractTarball(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		maxSize int64
+		files   map[string]string
+		wantErr bool
+	}{
+		{
+			name: "strips the top-level directory",
+			entries: []tarEntry{
+				{name: "owner-repo-abc/", typeflag: tar.TypeDir},
+				{name: "owner-repo-abc/README.md", typeflag: tar.TypeReg, content: "readme"},
+				{name: "owner-repo-abc/web/challenge.yml", typeflag: tar.TypeReg, content: "challenge"},
+			},
+			maxSize: 1024,
+			files:   map[string]string{"README.md": "readme", "web/challenge.yml": "challenge"},
+		},
+		{
+			name: "keeps traversal within the directory",
+			entries: []tarEntry{
+				{name: "owner-repo-abc/../../escape.txt", typeflag: tar.TypeReg, content: "a"},
+				{name: "owner-repo-abc/web/../../../../escape2.txt", typeflag: tar.TypeReg, content: "b"},
+				{name: "../escape3.txt", typeflag: tar.TypeReg, content: "c"},
+			},
+			maxSize: 1024,
+			files:   map[string]string{"escape.txt": "a", "escape2.txt": "b", "escape3.txt": "c"},
+		},
+		{
+			name: "skips links and files outside the top-level directory",
+			entries: []tarEntry{
+				{name: "owner-repo-abc/link", typeflag: tar.TypeSymlink, content: "/etc/passwd"},
+				{name: "owner-repo-abc/hard", typeflag: tar.TypeLink, content: "/etc/passwd"},
+				{name: "pax_global_header", typeflag: tar.TypeReg, content: "x"},
+				{name: "owner-repo-abc/file", typeflag: tar.TypeReg, content: "file"},
+			},
+			maxSize: 1024,
+			files:   map[string]string{"file": "file"},
+		},
+		{
+			name: "larger than the maximum size",
+			entries: []tarEntry{
+				{name: "owner-repo-abc/a", typeflag: tar.TypeReg, content: "12345"},
+				{name: "owner-repo-abc/b", typeflag: tar.TypeReg, content: "67890"},
+			},
+			maxSize: 8,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "snapshot")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractTarball(buildTarball(t, test.entries), dir, test.maxSize)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if files := readDirFiles(t, dir); !reflect.DeepEqual(files, test.files) {
+				t.Errorf("files = %v, want %v", files, test.files)
+			}
+			// Nothing may be written next to the snapshot
+			if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+				t.Errorf("%d entries written outside the snapshot", len(entries)-1)
+			}
+		})
+	}
+}
+
+func TestSnapshotCacheEviction(t *testing.T) {
+	cache, err := newSnapshotCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Download a snapshot with a single file of the given size
+	download := func(size int) func(dir string) error {
+		return func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "file"), []byte(strings.Repeat("x", size)), 0o644)
+		}
+	}
+	use := func(key string, size int) {
+		t.Helper()
+		err := cache.use(key, download(size), func(dir string) error {
+			_, err := os.Stat(filepath.Join(dir, "file"))
+			return err
+		})
+		if err != nil {
+			t.Fatalf("error using snapshot %s: %s", key, err)
+		}
+	}
+	keys := func() []string {
+		keys := []string{}
+		for key := range cache.snapshots {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return keys
+	}
+
+	use("a", 4)
+	use("b", 4)
+	use("a", 4) // a is now more recently used than b
+	use("c", 4)
+	if got := keys(); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("snapshots = %v, want [a c]", got)
+	}
+	if cache.size != 8 {
+		t.Errorf("size = %d, want 8", cache.size)
+	}
+
+	// Snapshots larger than the cache are not cached, and not downloaded again
+	err = cache.use("d", download(11), func(string) error { return nil })
+	if !errors.Is(err, errSnapshotUnavailable) {
+		t.Fatalf("expected errSnapshotUnavailable, got %v", err)
+	}
+	downloaded := false
+	err = cache.use("d", func(dir string) error {
+		downloaded = true
+		return nil
+	}, func(string) error { return nil })
+	if !errors.Is(err, errSnapshotUnavailable) || downloaded {
+		t.Errorf("failed snapshot was downloaded again (err %v)", err)
+	}
+	if got := keys(); !slices.Equal(got, []string{"a", "c"}) {
+		t.Errorf("snapshots = %v, want [a c]", got)
+	}
+
+	// Reopening the cache picks up the existing snapshots
+	reopened, err := newSnapshotCache(cache.dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.snapshots) != 2 || reopened.size != 8 {
+		t.Errorf("reopened cache has %d snapshots of %d bytes, want 2 of 8 bytes", len(reopened.snapshots), reopened.size)
+	}
+}
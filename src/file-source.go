@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Source of challenge files, such as a GitHub repository, a local directory or a bucket
+type FileSource interface {
+	// List the files in a directory at the given ref. Subdirectories are not included.
+	ListFiles(ref string, dir string) ([]SourceFile, error)
+	// Read the content of a file at the given ref
+	ReadFile(ref string, filePath string) ([]byte, error)
+	// Get the version of a file at the given ref, which changes whenever the content of the file changes
+	FileVersion(ref string, filePath string) (string, error)
+	// Whether the source supports refs. Sources without refs always serve their current files.
+	SupportsRefs() bool
+}
+
+// File in a file source
+type SourceFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	SHA  string `json:"sha"` // Version of the file, see FileSource.FileVersion
+}
+
+// Get the file source of a repository, selected by the scheme of the repository URL
+func newFileSource(repository string) (FileSource, error) {
+	switch {
+	case repository == "":
+		return nil, errors.New("no repository set, and GITHUB_REPO is not set")
+	case strings.HasPrefix(repository, "file://"):
+		return newLocalRepositoryFileSource(strings.TrimPrefix(repository, "file://"))
+	case strings.HasPrefix(repository, "git+https://"), strings.HasPrefix(repository, "git+ssh://"):
+		return newGitFileSource(strings.TrimPrefix(repository, "git+"))
+	case strings.HasPrefix(repository, "s3://"):
+		return newS3FileSource(strings.TrimPrefix(repository, "s3://"))
+	case strings.Contains(repository, "://") && !strings.HasPrefix(repository, "https://github.com/"):
+		return nil, fmt.Errorf("unsupported repository %q, valid schemes are: https://github.com/, file://, git+https://, git+ssh://, s3://", repository)
+	default:
+		return newGithubFileSource(repository)
+	}
+}
+
+// Get the file source of the challenge repository
+func challengeFileSource(challengeConfig *ChallengeConfig) (FileSource, error) {
+	source, err := newFileSource(challengeRepository(challengeConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	if challengeConfig.Commit != "" && !source.SupportsRefs() {
+		return nil, fmt.Errorf("challenge %s is pinned to commit %s, but its repository does not support commits", challengeConfig.Challenge.Slug, challengeConfig.Commit)
+	}
+
+	return source, nil
+}
+
+// Get the git blob SHA of the content, matching the version of the file in git based sources
+func gitBlobSHA(data []byte) string {
+	hash := sha1.New()
+	fmt.Fprintf(hash, "blob %d\x00", len(data))
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Clean a path within a file source, so it can not escape the root of the source
+func cleanSourcePath(filePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filePath), "/")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+type ChallengeFlag struct {
+	Flag           string `json:"flag"`
+	CaseSensitive  bool   `json:"case_sensitive"`
+	Type           string `json:"type,omitempty"`            // "static" (default), "regex" or a flag type provided by a CTFd plugin
+	Data           string `json:"data,omitempty"`            // Type specific data. Overrides case_sensitive for static and regex flags
+	TrimWhitespace bool   `json:"trim_whitespace,omitempty"` // Accept leading and trailing whitespace around a static flag
+}
+
+// Get the content, type and data of the flag as it is sent to CTFd
+func renderChallengeFlag(flag ChallengeFlag) (content string, flagType string, data string, err error) {
+	flagType = flag.Type
+	if flagType == "" {
+		flagType = "static"
+	}
+
+	content = flag.Flag
+	if content == "" {
+		return "", "", "", fmt.Errorf("flag of type %s has no content", flagType)
+	}
+
+	data = flag.Data
+	if flagType == "static" || flagType == "regex" {
+		if data == "" && !flag.CaseSensitive {
+			data = "case_insensitive" // Use case_insensitive if not case sensitive
+		}
+		if data != "" && data != "case_insensitive" {
+			return "", "", "", fmt.Errorf("invalid data %q for %s flag, only case_insensitive is supported", data, flagType)
+		}
+	}
+
+	switch flagType {
+	case "static":
+		if flag.TrimWhitespace {
+			// CTFd does not trim static flags, so match the flag with surrounding whitespace as a regex instead
+			content = `^\s*` + regexp.QuoteMeta(content) + `\s*$`
+			flagType = "regex"
+		}
+	case "regex":
+		if flag.TrimWhitespace {
+			return "", "", "", fmt.Errorf("trim_whitespace is only supported for static flags")
+		}
+		if err := validateRegexFlag(content); err != nil {
+			return "", "", "", fmt.Errorf("invalid regex flag %q: %w", content, err)
+		}
+	default:
+		if flag.TrimWhitespace {
+			return "", "", "", fmt.Errorf("trim_whitespace is only supported for static flags")
+		}
+		// Flag types provided by plugins are passed through as is
+	}
+
+	return content, flagType, data, nil
+}
+
+// Check a regex flag for syntax errors.
+// CTFd matches regex flags with Python, which supports lookarounds and backreferences, unlike Go.
+// These are replaced with groups Go supports before the pattern is parsed, so the rest of the pattern is still checked.
+func validateRegexFlag(pattern string) error {
+	_, err := syntax.Parse(replacePythonRegexConstructs(pattern), syntax.Perl)
+	return err
+}
+
+// Lookbehind and lookahead groups, which Go does not support
+var pythonLookaroundPrefixes = []string{"(?<=", "(?<!", "(?=", "(?!"}
+
+// Named backreference, such as (?P=name)
+var pythonNamedBackreferencePattern = regexp.MustCompile(`^\(\?P=[A-Za-z_][A-Za-z0-9_]*\)`)
+
+// Numbered backreference, such as \1
+var pythonBackreferencePattern = regexp.MustCompile(`^\\[1-9][0-9]?`)
+
+// Replace the lookarounds of a Python regex with non-capturing groups, and its backreferences with empty groups.
+// Character classes are left as is.
+func replacePythonRegexConstructs(pattern string) string {
+	var result strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); {
+		rest := pattern[i:]
+
+		if !inClass {
+			if match := pythonBackreferencePattern.FindString(rest); match != "" {
+				result.WriteString("(?:)")
+				i += len(match)
+				continue
+			}
+			if match := pythonNamedBackreferencePattern.FindString(rest); match != "" {
+				result.WriteString("(?:)")
+				i += len(match)
+				continue
+			}
+			if index := slices.IndexFunc(pythonLookaroundPrefixes, func(prefix string) bool {
+				return strings.HasPrefix(rest, prefix)
+			}); index >= 0 {
+				result.WriteString("(?:")
+				i += len(pythonLookaroundPrefixes[index])
+				continue
+			}
+		}
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			// Copy escapes as is, so escaped brackets do not start or end a character class
+			result.WriteString(rest[:2])
+			i += 2
+			continue
+		case rest[0] == '[' && !inClass:
+			inClass = true
+			// A closing bracket right after the opening bracket is a literal
+			start := 1
+			if strings.HasPrefix(rest[1:], "^") {
+				start = 2
+			}
+			if strings.HasPrefix(rest[start:], "]") {
+				start++
+			}
+			result.WriteString(rest[:start])
+			i += start
+			continue
+		case rest[0] == ']' && inClass:
+			inClass = false
+		}
+
+		result.WriteByte(rest[0])
+		i++
+	}
+	return result.String()
+}
+
+// Check that all flags of a challenge are valid, before anything is uploaded
+func validateChallengeFlags(challenge *ChallengeConfig) error {
+	for i, flag := range challenge.Challenge.Flag {
+		if _, _, _, err := renderChallengeFlag(flag); err != nil {
+			return fmt.Errorf("flag %d of challenge %s: %w", i, challenge.Challenge.Slug, err)
+		}
+	}
+	return nil
+}
+
+func uploadCTFdChallengeFlags(challengeID int, challenge *ChallengeConfig, client *ctfd.Client) error {
+	for _, flag := range challenge.Challenge.Flag {
+		content, flagType, data, err := renderChallengeFlag(flag)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.PostFlags(&ctfd.PostFlagsParams{
+			Challenge: challengeID,
+			Content:   content,
+			Type:      flagType,
+			Data:      data,
+		})
+		if err != nil {
+			log.Printf("Error uploading flag: %s\n", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import "testing"
+
+func TestRenderChallengeFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     ChallengeFlag
+		content  string
+		flagType string
+		data     string
+		wantErr  bool
+	}{
+		{
+			name:     "static case sensitive",
+			flag:     ChallengeFlag{Flag: "flag{a}", CaseSensitive: true},
+			content:  "flag{a}",
+			flagType: "static",
+		},
+		{
+			name:     "static case insensitive",
+			flag:     ChallengeFlag{Flag: "flag{a}"},
+			content:  "flag{a}",
+			flagType: "static",
+			data:     "case_insensitive",
+		},
+		{
+			name:     "data overrides case_sensitive",
+			flag:     ChallengeFlag{Flag: "flag{a}", CaseSensitive: true, Data: "case_insensitive"},
+			content:  "flag{a}",
+			flagType: "static",
+			data:     "case_insensitive",
+		},
+		{
+			name:     "static trim whitespace",
+			flag:     ChallengeFlag{Flag: "flag{a.b}", CaseSensitive: true, TrimWhitespace: true},
+			content:  `^\s*flag\{a\.b\}\s*$`,
+			flagType: "regex",
+		},
+		{
+			name:     "regex",
+			flag:     ChallengeFlag{Flag: `flag\{[a-z]+\}`, Type: "regex", CaseSensitive: true},
+			content:  `flag\{[a-z]+\}`,
+			flagType: "regex",
+		},
+		{
+			name:     "regex with lookahead",
+			flag:     ChallengeFlag{Flag: `flag\{(?=.*x).+\}`, Type: "regex", CaseSensitive: true},
+			content:  `flag\{(?=.*x).+\}`,
+			flagType: "regex",
+		},
+		{
+			name:     "plugin type",
+			flag:     ChallengeFlag{Flag: "flag{a}", Type: "custom", Data: "anything"},
+			content:  "flag{a}",
+			flagType: "custom",
+			data:     "anything",
+		},
+		{
+			name:    "no content",
+			flag:    ChallengeFlag{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid data",
+			flag:    ChallengeFlag{Flag: "flag{a}", Data: "other"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			flag:    ChallengeFlag{Flag: `flag\{[a-z+\}`, Type: "regex"},
+			wantErr: true,
+		},
+		{
+			name:    "regex trim whitespace",
+			flag:    ChallengeFlag{Flag: `flag`, Type: "regex", TrimWhitespace: true},
+			wantErr: true,
+		},
+		{
+			name:    "plugin type trim whitespace",
+			flag:    ChallengeFlag{Flag: "flag{a}", Type: "custom", TrimWhitespace: true},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, flagType, data, err := renderChallengeFlag(test.flag)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got content %q", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if content != test.content || flagType != test.flagType || data != test.data {
+				t.Errorf("got (%q, %q, %q), want (%q, %q, %q)", content, flagType, data, test.content, test.flagType, test.data)
+			}
+		})
+	}
+}
+
+func TestValidateRegexFlag(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: `^flag\{[0-9a-f]{32}\}$`},
+		{pattern: `flag\{(?=.*\d)\w+\}`},
+		{pattern: `flag\{(?<!x)\w+\}`},
+		{pattern: `flag\{(\w)\1\}`},
+		{pattern: `flag\{(?P<inner>\w+)(?P=inner)\}`},
+		{pattern: `flag\{[a-z\}`, wantErr: true},
+		{pattern: `flag\{(\w+\}`, wantErr: true},
+		{pattern: `*flag`, wantErr: true},
+		{pattern: `flag\{\q\}`, wantErr: true},
+		{pattern: `flag\{(?=\w)\q\}`, wantErr: true},
+		{pattern: `flag\{(\w)\1(\w+\}`, wantErr: true},
+		{pattern: `flag\{(?P=\}`, wantErr: true},
+		{pattern: `flag\{[(?=]\}`},
+		{pattern: `flag\{[\]\\1]\}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			err := validateRegexFlag(test.pattern)
+			if test.wantErr && err == nil {
+				t.Error("expected an error")
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v70/github"
 )
@@ -14,11 +17,25 @@ var githubClient *github.Client
 func initGithubClient() {
 	log.Println("Initializing GitHub client...")
 
+	// Without a token, only public repositories can be accessed, with a lower rate limit
+	token := getGithubToken()
+	if token == "" {
+		log.Println("GITHUB_TOKEN is not set, using unauthenticated GitHub client")
+		githubClient = github.NewClient(nil)
+		return
+	}
+
 	// Initialize the GitHub client
-	githubClient = github.NewClient(nil).WithAuthToken(getGithubToken())
+	githubClient = github.NewClient(nil).WithAuthToken(token)
+
+	user := getGithubUser()
+	if user == "" {
+		log.Println("GitHub client initialized, GITHUB_USER is not set so access is not checked")
+		return
+	}
 
 	// Check access to the GitHub API
-	_, _, err := githubClient.Users.Get(context.Background(), getGithubUser())
+	_, _, err := githubClient.Users.Get(context.Background(), user)
 	if err != nil {
 		log.Println("Error checking access to GitHub API:", err)
 		return
@@ -50,6 +67,24 @@ func getGithubDirContents(repo, branch, path string) ([]*github.RepositoryConten
 	return contents, nil
 }
 
+// Get the blob SHA of a file, which changes whenever the content of the file changes
+func getGithubFileSHA(repo, branch, path string) (string, error) {
+	owner, repo := splitRepo(repo)
+
+	fileContent, _, _, err := githubClient.Repositories.GetContents(context.Background(), owner, repo, path, &github.RepositoryContentGetOptions{
+		Ref: branch,
+	})
+	if err != nil {
+		log.Printf("Error getting file contents: %s\n", err)
+		return "", err
+	}
+	if fileContent == nil {
+		return "", fmt.Errorf("%s is not a file", path)
+	}
+
+	return fileContent.GetSHA(), nil
+}
+
 func getGithubFileBytes(repo, branch, path string) (*string, error) {
 	owner, repo := splitRepo(repo)
 
@@ -61,6 +96,9 @@ func getGithubFileBytes(repo, branch, path string) (*string, error) {
 		log.Printf("Error getting file contents: %s\n", err)
 		return nil, err
 	}
+	if fileContent == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
 
 	// If file is larger than 1 MB, use DownloadContents
 	if fileContent.GetSize() > 1024*1024 {
@@ -90,3 +128,42 @@ func getGithubFileBytes(repo, branch, path string) (*string, error) {
 
 	return &decodedContent, nil
 }
+
+// Get the commit SHA a branch, tag or commit of the repository points to
+func getGithubCommitSHA(repo, ref string) (string, error) {
+	owner, repo := splitRepo(repo)
+
+	sha, _, err := githubClient.Repositories.GetCommitSHA1(context.Background(), owner, repo, ref, "")
+	if err != nil {
+		log.Printf("Error getting commit of %s: %s\n", ref, err)
+		return "", err
+	}
+
+	return sha, nil
+}
+
+// Download the gzipped tarball of the repository at the commit
+func downloadGithubTarball(repo, commit string) (io.ReadCloser, error) {
+	owner, repo := splitRepo(repo)
+
+	link, _, err := githubClient.Repositories.GetArchiveLink(context.Background(), owner, repo, github.Tarball, &github.RepositoryContentGetOptions{
+		Ref: commit,
+	}, 1)
+	if err != nil {
+		log.Printf("Error getting tarball link: %s\n", err)
+		return nil, err
+	}
+
+	// The link is signed, so no authentication is needed
+	res, err := (&http.Client{Timeout: 10 * time.Minute}).Get(link.String())
+	if err != nil {
+		log.Printf("Error downloading tarball: %s\n", err)
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("error downloading tarball: %s", res.Status)
+	}
+
+	return res.Body, nil
+}
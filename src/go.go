@@ -3,9 +3,16 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		runPlanCommand()
+		return
+	}
+
 	HEALTH.Store(true)
 
 	http.HandleFunc("/", indexHandler)
@@ -19,6 +26,12 @@ func main() {
 	http.HandleFunc("/api/ctfd/challenges/init", postUploadChallengesHandler)
 	http.HandleFunc("/api/ctfd/challenges", getCTFdChallengesHandler)
 	http.HandleFunc("/api/ctfd/challenges/uploaded", getCTFdUploadedChallengesHandler)
+	http.HandleFunc("/api/ctfd/reconcile", reconcileHandler)
+	http.HandleFunc("/api/ctfd/plan", getPlanHandler)
+	http.HandleFunc("/api/ctfd/drift", getDriftHandler)
+	http.HandleFunc("/api/ctfd/drift/{slug}/overrides", challengeOverridesHandler)
+
+	http.HandleFunc("/api/sync/status", getSyncStatusHandler)
 
 	http.HandleFunc("/api/version", versionHandler)
 	http.HandleFunc("/api/status", statusHandler)
@@ -31,13 +44,23 @@ func main() {
 		log.Fatalf("Error initializing cluster client: %s", err)
 	}
 
-	go func() {
+	err = initStateStores(true)
+	if err != nil {
+		log.Fatalf("Error initializing state stores: %s", err)
+	}
+	initEventRecorder()
+
+	// Only the leader runs the background workers, which write to CTFd
+	go runAsLeader(func() {
+		go initBackgroundReconciler()
+		go initBackgroundOrphanCleanup()
+
 		err := initBackgroundChallengeWatcher()
 		if err != nil {
 			log.Printf("Error initializing background challenge watcher: %s", err)
 			setUnhealthy()
 		}
-	}()
+	})
 
 	log.Println("CTFd Manager started")
 
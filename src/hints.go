@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+type ChallengeHint struct {
+	Title        string `json:"title,omitempty"`
+	Content      string `json:"content"`
+	Cost         int    `json:"cost"`
+	Requirements []int  `json:"requirements,omitempty"` // Indices of earlier hints that must be unlocked first
+}
+
+// Check that the hints of a challenge are valid, before anything is changed in CTFd
+func validateChallengeHints(challenge *ChallengeConfig) error {
+	for i, hint := range challenge.Challenge.Hints {
+		if hint.Content == "" {
+			return fmt.Errorf("hint %d of challenge %s has no content", i, challenge.Challenge.Slug)
+		}
+		if hint.Cost < 0 {
+			return fmt.Errorf("hint %d of challenge %s has a negative cost", i, challenge.Challenge.Slug)
+		}
+		for _, requirement := range hint.Requirements {
+			if requirement < 0 || requirement >= i {
+				return fmt.Errorf("hint %d of challenge %s requires hint %d, but may only require earlier hints", i, challenge.Challenge.Slug, requirement)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the hints of a challenge in CTFd, including their content, ordered by creation
+func getCTFdChallengeHints(challengeID int, client *ctfd.Client) ([]*ctfd.Hint, error) {
+	// The hint list does not include the content, so each hint is fetched individually
+	list, err := client.GetHints(&ctfd.GetHintsParams{
+		ChallengeID: &challengeID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hints := []*ctfd.Hint{}
+	for _, item := range list {
+		hint, err := client.GetHint(strconv.Itoa(item.ID))
+		if err != nil {
+			return nil, err
+		}
+		hints = append(hints, hint)
+	}
+
+	sort.Slice(hints, func(i, j int) bool {
+		return hints[i].ID < hints[j].ID
+	})
+
+	return hints, nil
+}
+
+// Match the desired hints to the hints of a challenge in CTFd, returning the matched CTFd hint of each desired hint, or nil if it is new.
+// Hints are matched by title and content, then by title alone, so inserting or reordering hints never moves the content of a hint teams already unlocked.
+func matchCTFdChallengeHints(live []*ctfd.Hint, hints []ChallengeHint) []*ctfd.Hint {
+	matched := make([]*ctfd.Hint, len(hints))
+	used := map[int]bool{}
+
+	match := func(same func(live *ctfd.Hint, hint ChallengeHint) bool) {
+		for i, hint := range hints {
+			if matched[i] != nil {
+				continue
+			}
+			for _, candidate := range live {
+				if !used[candidate.ID] && same(candidate, hint) {
+					matched[i] = candidate
+					used[candidate.ID] = true
+					break
+				}
+			}
+		}
+	}
+	match(func(live *ctfd.Hint, hint ChallengeHint) bool {
+		return stringPointerValue(live.Title) == hint.Title && stringPointerValue(live.Content) == hint.Content
+	})
+	match(func(live *ctfd.Hint, hint ChallengeHint) bool {
+		return hint.Title != "" && stringPointerValue(live.Title) == hint.Title
+	})
+
+	return matched
+}
+
+// Get the hints of a challenge in CTFd that are not matched by any desired hint
+func unmatchedCTFdChallengeHints(live []*ctfd.Hint, matched []*ctfd.Hint) []*ctfd.Hint {
+	used := map[int]bool{}
+	for _, hint := range matched {
+		if hint != nil {
+			used[hint.ID] = true
+		}
+	}
+
+	unmatched := []*ctfd.Hint{}
+	for _, hint := range live {
+		if !used[hint.ID] {
+			unmatched = append(unmatched, hint)
+		}
+	}
+	return unmatched
+}
+
+// Sync the hints of a challenge to CTFd.
+// Existing hints are patched instead of re-created, and new hints are appended, so hints already unlocked by teams stay unlocked.
+func syncCTFdChallengeHints(challengeID int, challenge *ChallengeConfig, client *ctfd.Client) error {
+	existing, err := getCTFdChallengeHints(challengeID, client)
+	if err != nil {
+		log.Printf("Error getting challenge hints: %s\n", err)
+		return err
+	}
+	matched := matchCTFdChallengeHints(existing, challenge.Challenge.Hints)
+
+	// IDs of the synced hints, used to resolve the requirements of later hints
+	hintIDs := []int{}
+
+	for i, hint := range challenge.Challenge.Hints {
+		requirements := ctfd.Requirements{
+			Prerequisites: hintRequirementIDs(hint, hintIDs),
+		}
+		var title *string
+		if hint.Title != "" {
+			title = &hint.Title
+		}
+
+		if current := matched[i]; current != nil {
+			// Patch the existing hint, if it differs
+			if hintMatches(current, hint, hintIDs) {
+				hintIDs = append(hintIDs, current.ID)
+				continue
+			}
+
+			_, err := client.PatchHint(strconv.Itoa(current.ID), &ctfd.PatchHintsParams{
+				ChallengeID:  challengeID,
+				Title:        title,
+				Content:      hint.Content,
+				Cost:         hint.Cost,
+				Requirements: requirements,
+			})
+			if err != nil {
+				log.Printf("Error updating hint %d: %s\n", i, err)
+				return err
+			}
+			log.Printf("Updated hint %d of challenge %s\n", i, challenge.Challenge.Slug)
+			hintIDs = append(hintIDs, current.ID)
+			continue
+		}
+
+		// Create the new hint
+		created, err := client.PostHints(&ctfd.PostHintsParams{
+			ChallengeID:  challengeID,
+			Title:        title,
+			Content:      hint.Content,
+			Cost:         hint.Cost,
+			Requirements: requirements,
+		})
+		if err != nil {
+			log.Printf("Error uploading hint %d: %s\n", i, err)
+			return err
+		}
+		log.Printf("Uploaded hint %d of challenge %s\n", i, challenge.Challenge.Slug)
+		hintIDs = append(hintIDs, created.ID)
+	}
+
+	// Delete the hints that were removed from the challenge
+	for _, hint := range unmatchedCTFdChallengeHints(existing, matched) {
+		err := client.DeleteHint(strconv.Itoa(hint.ID))
+		if err != nil {
+			log.Printf("Error deleting hint %d: %s\n", hint.ID, err)
+			return err
+		}
+		log.Printf("Deleted hint %d of challenge %s\n", hint.ID, challenge.Challenge.Slug)
+	}
+
+	return nil
+}
+
+// Resolve the hint indices required by a hint to the IDs of the earlier synced hints
+func hintRequirementIDs(hint ChallengeHint, hintIDs []int) []int {
+	ids := []int{}
+	for _, requirement := range hint.Requirements {
+		if requirement >= 0 && requirement < len(hintIDs) {
+			ids = append(ids, hintIDs[requirement])
+		}
+	}
+	return ids
+}
+
+// Check if the CTFd hint matches the desired hint
+func hintMatches(live *ctfd.Hint, hint ChallengeHint, hintIDs []int) bool {
+	if stringPointerValue(live.Title) != hint.Title || stringPointerValue(live.Content) != hint.Content || live.Cost != hint.Cost {
+		return false
+	}
+
+	livePrerequisites := []int{}
+	if live.Requirements != nil {
+		livePrerequisites = live.Requirements.Prerequisites
+	}
+	return equalIntSets(livePrerequisites, hintRequirementIDs(hint, hintIDs))
+}
+
+// Compare the hints of a challenge in CTFd with the desired hints.
+// Returns the positions of the desired hints that would be created or updated, and the CTFd hints that would be deleted.
+func diffCTFdChallengeHints(live []*ctfd.Hint, hints []ChallengeHint) ([]int, []*ctfd.Hint) {
+	matched := matchCTFdChallengeHints(live, hints)
+
+	differing := []int{}
+	hintIDs := []int{}
+	for i, hint := range hints {
+		if matched[i] == nil {
+			differing = append(differing, i)
+			// New hints get an ID once created, so requirements on them always differ
+			hintIDs = append(hintIDs, 0)
+			continue
+		}
+		if !hintMatches(matched[i], hint, hintIDs) {
+			differing = append(differing, i)
+		}
+		hintIDs = append(hintIDs, matched[i].ID)
+	}
+
+	return differing, unmatchedCTFdChallengeHints(live, matched)
+}
+
+// Convert CTFd hints to the challenge hint format, resolving requirements to hint positions
+func convertCTFdChallengeHints(live []*ctfd.Hint) []ChallengeHint {
+	positions := map[int]int{}
+	for i, hint := range live {
+		positions[hint.ID] = i
+	}
+
+	hints := []ChallengeHint{}
+	for _, hint := range live {
+		converted := ChallengeHint{
+			Title:   stringPointerValue(hint.Title),
+			Content: stringPointerValue(hint.Content),
+			Cost:    hint.Cost,
+		}
+		if hint.Requirements != nil {
+			for _, id := range hint.Requirements.Prerequisites {
+				if position, ok := positions[id]; ok {
+					converted.Requirements = append(converted.Requirements, position)
+				}
+			}
+		}
+		hints = append(hints, converted)
+	}
+	return hints
+}
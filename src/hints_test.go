@@ -0,0 +1,134 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+// Create a CTFd hint, requiring the hints with the given IDs
+func liveHint(id int, title string, content string, cost int, prerequisites ...int) *ctfd.Hint {
+	hint := &ctfd.Hint{
+		ID:      id,
+		Title:   &title,
+		Content: &content,
+		Cost:    cost,
+	}
+	if len(prerequisites) > 0 {
+		hint.Requirements = &ctfd.Requirements{Prerequisites: prerequisites}
+	}
+	return hint
+}
+
+func TestDiffCTFdChallengeHints(t *testing.T) {
+	live := []*ctfd.Hint{
+		liveHint(1, "First", "first hint", 10),
+		liveHint(2, "Second", "second hint", 20, 1),
+	}
+
+	tests := []struct {
+		name      string
+		live      []*ctfd.Hint
+		hints     []ChallengeHint
+		differing []int
+		removed   []int
+	}{
+		{
+			name: "unchanged",
+			live: live,
+			hints: []ChallengeHint{
+				{Title: "First", Content: "first hint", Cost: 10},
+				{Title: "Second", Content: "second hint", Cost: 20, Requirements: []int{0}},
+			},
+			differing: []int{},
+		},
+		{
+			name: "hint inserted before the others",
+			live: live,
+			hints: []ChallengeHint{
+				{Title: "New", Content: "new hint"},
+				{Title: "First", Content: "first hint", Cost: 10},
+				{Title: "Second", Content: "second hint", Cost: 20, Requirements: []int{1}},
+			},
+			differing: []int{0},
+		},
+		{
+			name: "hints reordered",
+			live: []*ctfd.Hint{
+				liveHint(1, "First", "first hint", 10),
+				liveHint(2, "Second", "second hint", 20),
+			},
+			hints: []ChallengeHint{
+				{Title: "Second", Content: "second hint", Cost: 20},
+				{Title: "First", Content: "first hint", Cost: 10},
+			},
+			differing: []int{},
+		},
+		{
+			name: "content changed",
+			live: live,
+			hints: []ChallengeHint{
+				{Title: "First", Content: "changed hint", Cost: 10},
+				{Title: "Second", Content: "second hint", Cost: 20, Requirements: []int{0}},
+			},
+			differing: []int{0},
+		},
+		{
+			name: "cost and requirements changed",
+			live: live,
+			hints: []ChallengeHint{
+				{Title: "First", Content: "first hint", Cost: 15},
+				{Title: "Second", Content: "second hint", Cost: 20},
+			},
+			differing: []int{0, 1},
+		},
+		{
+			name: "untitled content changed",
+			live: []*ctfd.Hint{
+				liveHint(1, "", "first hint", 0),
+			},
+			hints: []ChallengeHint{
+				{Content: "changed hint"},
+			},
+			differing: []int{0},
+			removed:   []int{1},
+		},
+		{
+			name: "hint removed",
+			live: live,
+			hints: []ChallengeHint{
+				{Title: "First", Content: "first hint", Cost: 10},
+			},
+			differing: []int{},
+			removed:   []int{2},
+		},
+		{
+			name: "requires a new hint",
+			live: []*ctfd.Hint{
+				liveHint(2, "Second", "second hint", 20),
+			},
+			hints: []ChallengeHint{
+				{Title: "First", Content: "first hint", Cost: 10},
+				{Title: "Second", Content: "second hint", Cost: 20, Requirements: []int{0}},
+			},
+			differing: []int{0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			differing, removed := diffCTFdChallengeHints(test.live, test.hints)
+			if !slices.Equal(differing, test.differing) {
+				t.Errorf("differing = %v, want %v", differing, test.differing)
+			}
+			removedIDs := []int{}
+			for _, hint := range removed {
+				removedIDs = append(removedIDs, hint.ID)
+			}
+			if !slices.Equal(removedIDs, test.removed) {
+				t.Errorf("removed = %v, want %v", removedIDs, test.removed)
+			}
+		})
+	}
+}
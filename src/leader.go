@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var leading atomic.Bool
+var leaderElector *leaderelection.LeaderElector
+
+var errNotLeader = errors.New("not the leader")
+
+// Run the given function once this replica is the leader.
+// Without leader election enabled, the replica is always the leader.
+func runAsLeader(run func()) {
+	if !getLeaderElectionEnabled() {
+		leading.Store(true)
+		run()
+		return
+	}
+
+	identity := getPodName()
+	log.Printf("Starting leader election for lease %s as %s...\n", getLeaderElectionLease(), identity)
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      getLeaderElectionLease(),
+			Namespace: getNamespace(),
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Println("Acquired leadership, starting background workers")
+				leading.Store(true)
+				run()
+			},
+			OnStoppedLeading: func() {
+				// The background workers can not be stopped cleanly, so restart to become a follower
+				leading.Store(false)
+				log.Fatal("Lost leadership, restarting")
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.Printf("Current leader is %s\n", leader)
+				}
+			},
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error creating leader elector: %s", err)
+	}
+	leaderElector = elector
+
+	elector.Run(context.Background())
+}
+
+func isLeader() bool {
+	return leading.Load()
+}
+
+func getLeader() string {
+	if leaderElector == nil {
+		return getPodName()
+	}
+	return leaderElector.GetLeader()
+}
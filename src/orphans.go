@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	OrphanPolicyHide   = "hide"   // Hide orphaned challenges and pages in CTFd
+	OrphanPolicyDelete = "delete" // Delete orphaned challenges and pages from CTFd
+	OrphanPolicyIgnore = "ignore" // Leave orphaned challenges and pages in CTFd
+)
+
+func isValidOrphanPolicy(policy string) bool {
+	return policy == OrphanPolicyHide || policy == OrphanPolicyDelete || policy == OrphanPolicyIgnore
+}
+
+// Clean up orphans on startup, and again on every watcher resync
+func initBackgroundOrphanCleanup() {
+	log.Printf("Initializing background orphan cleanup with policy %s...\n", getOrphanPolicy())
+
+	if err := cleanupOrphans(); err != nil {
+		log.Printf("Error cleaning up orphans: %s\n", err)
+	}
+
+	interval := getWatcherResyncInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := cleanupOrphans(); err != nil {
+			log.Printf("Error cleaning up orphans: %s\n", err)
+		}
+	}
+}
+
+// Find the challenges and pages in the state stores that are still live in CTFd, but no longer have a ConfigMap.
+// Fails if any ConfigMap can not be parsed, as its challenge or page would otherwise be reported as an orphan.
+func findOrphans(challengeConfigMaps []corev1.ConfigMap, pageConfigMaps []corev1.ConfigMap) ([]PlanAction, error) {
+	orphans := []PlanAction{}
+
+	challengeSlugs := make(map[string]bool)
+	for i := range challengeConfigMaps {
+		challengeConfig, err := extractChallengeConfigMap(&challengeConfigMaps[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid challenge configmap %s: %w", challengeConfigMaps[i].Name, err)
+		}
+		challengeSlugs[challengeConfig.Challenge.Slug] = true
+	}
+
+	pageSlugs := make(map[string]bool)
+	for i := range pageConfigMaps {
+		pageConfig, err := extractPageConfigMap(&pageConfigMaps[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid page configmap %s: %w", pageConfigMaps[i].Name, err)
+		}
+		pageSlugs[pageConfig.Page.Slug] = true
+	}
+
+	uploadedChallenges, err := getUploadedCTFdChallenges()
+	if err != nil {
+		return nil, err
+	}
+	for slug, id := range uploadedChallenges {
+		if id == "" || id == "0" || challengeSlugs[slug] {
+			continue
+		}
+		idInt, _ := strconv.Atoi(id)
+		orphans = append(orphans, PlanAction{Kind: "challenge", Slug: slug, ID: idInt, Action: "orphan"})
+	}
+
+	uploadedPages, err := getUploadedCTFdPages()
+	if err != nil {
+		return nil, err
+	}
+	for slug, id := range uploadedPages {
+		if id == "" || id == "0" || pageSlugs[slug] {
+			continue
+		}
+		idInt, _ := strconv.Atoi(id)
+		orphans = append(orphans, PlanAction{Kind: "page", Slug: slug, ID: idInt, Action: "orphan"})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		return orphans[i].Slug < orphans[j].Slug
+	})
+
+	return orphans, nil
+}
+
+// Hide or delete the challenges and pages whose ConfigMap was deleted while the manager was not watching,
+// and prune the hashes of ConfigMaps that no longer exist
+func cleanupOrphans() error {
+	challengeConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return err
+	}
+
+	pageConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "page-config"})
+	if err != nil {
+		log.Printf("Error listing page configmaps: %s\n", err)
+		return err
+	}
+
+	if err := pruneConfigmapHashes(append(challengeConfigMaps, pageConfigMaps...)); err != nil {
+		log.Printf("Error pruning configmap hashes: %s\n", err)
+		return err
+	}
+
+	policy := getOrphanPolicy()
+	if policy == OrphanPolicyIgnore {
+		return nil
+	}
+
+	orphans, err := findOrphans(challengeConfigMaps, pageConfigMaps)
+	if err != nil {
+		return err
+	}
+
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	// Index the live challenges and pages, to know which orphans are already hidden or gone
+	challenges, err := getCTFdChallenges()
+	if err != nil {
+		log.Printf("Error getting CTFd challenges: %s\n", err)
+		return err
+	}
+	liveChallenges := make(map[int]*ctfd.Challenge)
+	for _, challenge := range challenges {
+		liveChallenges[challenge.ID] = challenge
+	}
+
+	pages, err := getCTFdPages()
+	if err != nil {
+		log.Printf("Error getting CTFd pages: %s\n", err)
+		return err
+	}
+	livePages := make(map[int]*ctfd.Page)
+	for _, page := range pages {
+		livePages[page.ID] = page
+	}
+
+	for _, orphan := range orphans {
+		if err := cleanupOrphan(orphan, policy, liveChallenges, livePages); err != nil {
+			log.Printf("Error cleaning up orphaned %s %s: %s\n", orphan.Kind, orphan.Slug, err)
+		}
+	}
+
+	return nil
+}
+
+func cleanupOrphan(orphan PlanAction, policy string, liveChallenges map[int]*ctfd.Challenge, livePages map[int]*ctfd.Page) error {
+	if orphan.Kind == "page" {
+		return cleanupOrphanedPage(orphan, policy, livePages)
+	}
+
+	live, ok := liveChallenges[orphan.ID]
+	if !ok {
+		log.Printf("Challenge %s (%d) no longer exists in CTFd, removing it from state\n", orphan.Slug, orphan.ID)
+		return deleteUploadedCTFdChallenge(orphan.Slug)
+	}
+
+	if policy == OrphanPolicyDelete {
+		log.Printf("Challenge %s has no configmap, applying orphan policy %s\n", orphan.Slug, policy)
+		return deleteCTFdChallenge(orphan.Slug)
+	}
+
+	// Hidden challenges keep their ID, so they are updated if the configmap returns.
+	// Skip challenges that are already hidden, to not hide them again on every resync.
+	if live.State == "hidden" {
+		return nil
+	}
+
+	log.Printf("Challenge %s has no configmap, applying orphan policy %s\n", orphan.Slug, policy)
+	return disableCTFdChallenge(orphan.Slug)
+}
+
+func cleanupOrphanedPage(orphan PlanAction, policy string, livePages map[int]*ctfd.Page) error {
+	live, ok := livePages[orphan.ID]
+	if !ok {
+		log.Printf("Page %s (%d) no longer exists in CTFd, removing it from state\n", orphan.Slug, orphan.ID)
+		return deleteUploadedCTFdPage(orphan.Slug)
+	}
+
+	if policy == OrphanPolicyDelete {
+		log.Printf("Page %s has no configmap, applying orphan policy %s\n", orphan.Slug, policy)
+		return deleteCTFdPage(orphan.Slug)
+	}
+
+	// Skip pages that are already hidden, to not hide them again on every resync
+	if live.Hidden {
+		return nil
+	}
+
+	log.Printf("Page %s has no configmap, applying orphan policy %s\n", orphan.Slug, policy)
+	return disableCTFdPage(orphan.Slug)
+}
+
+// Remove the stored hashes of configmaps that no longer exist
+func pruneConfigmapHashes(configMaps []corev1.ConfigMap) error {
+	existing := make(map[string]bool)
+	for _, configMap := range configMaps {
+		existing[configMap.Name] = true
+	}
+
+	hashes, err := getConfigmapHashSet()
+	if err != nil {
+		return err
+	}
+
+	for name := range hashes {
+		if existing[name] {
+			continue
+		}
+		if err := hashStore.Delete(name); err != nil {
+			return err
+		}
+		log.Printf("Pruned stored hash of deleted configmap %s\n", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type PlanAction struct {
+	Kind      string      `json:"kind"` // "challenge" or "page"
+	ConfigMap string      `json:"configmap,omitempty"`
+	Slug      string      `json:"slug"`
+	ID        int         `json:"id,omitempty"`
+	Action    string      `json:"action"` // "create", "update", "hide", "none" or "orphan"
+	Diffs     []FieldDiff `json:"diffs,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// What a sync of all challenge and page ConfigMaps would change in CTFd
+type Plan struct {
+	GeneratedAt time.Time    `json:"generated_at"`
+	Challenges  []PlanAction `json:"challenges"`
+	Pages       []PlanAction `json:"pages"`
+	Orphans     []PlanAction `json:"orphans"`
+	OrphanError string       `json:"orphan_error,omitempty"` // Set if orphans could not be determined
+}
+
+// Compute the plan of a sync, without writing anything to CTFd or the state stores
+func computePlan() (*Plan, error) {
+	plan := &Plan{
+		GeneratedAt: time.Now(),
+		Challenges:  []PlanAction{},
+		Pages:       []PlanAction{},
+		Orphans:     []PlanAction{},
+	}
+
+	client, err := getCTFdClient()
+	if err != nil {
+		log.Printf("Error getting CTFd client: %s\n", err)
+		return nil, err
+	}
+
+	challengeConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return nil, err
+	}
+
+	pageConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "page-config"})
+	if err != nil {
+		log.Printf("Error listing page configmaps: %s\n", err)
+		return nil, err
+	}
+
+	mappingMap, err := getMappingMap(getNamespace())
+	if err != nil {
+		log.Printf("Error getting mapping map: %s\n", err)
+		return nil, err
+	}
+
+	for i := range challengeConfigMaps {
+		plan.Challenges = append(plan.Challenges, planChallenge(&challengeConfigMaps[i], client, mappingMap))
+	}
+
+	for i := range pageConfigMaps {
+		plan.Pages = append(plan.Pages, planPage(&pageConfigMaps[i], client))
+	}
+
+	orphans, err := findOrphans(challengeConfigMaps, pageConfigMaps)
+	if err != nil {
+		log.Printf("Error finding orphans: %s\n", err)
+		plan.OrphanError = err.Error()
+	} else {
+		plan.Orphans = orphans
+	}
+
+	return plan, nil
+}
+
+func planChallenge(configMap *corev1.ConfigMap, client *ctfd.Client, mappingMap MappingMap) PlanAction {
+	action := PlanAction{
+		Kind:      "challenge",
+		ConfigMap: configMap.Name,
+	}
+
+	challengeConfig, err := extractChallengeConfigMap(configMap)
+	if err != nil {
+		action.Error = err.Error()
+		return action
+	}
+	action.Slug = challengeConfig.Challenge.Slug
+
+	uploadedChallengeID, _ := getUploadedCTFdChallenge(challengeConfig.Challenge.Slug)
+	id, err := strconv.Atoi(uploadedChallengeID)
+	if err != nil || id == 0 {
+		action.Action = "create"
+		return action
+	}
+	action.ID = id
+
+	live, err := client.GetChallenge(id)
+	if err != nil {
+		// Challenges are re-created when missing
+		action.Action = "create"
+		return action
+	}
+
+	action.Diffs, err = diffCTFdChallenge(challengeConfig, live, client, mappingMap)
+	if err != nil {
+		action.Error = err.Error()
+		return action
+	}
+	action.Action = planActionForDiffs(action.Diffs)
+
+	return action
+}
+
+func planPage(configMap *corev1.ConfigMap, client *ctfd.Client) PlanAction {
+	action := PlanAction{
+		Kind:      "page",
+		ConfigMap: configMap.Name,
+	}
+
+	pageConfig, err := extractPageConfigMap(configMap)
+	if err != nil {
+		action.Error = err.Error()
+		return action
+	}
+	action.Slug = pageConfig.Page.Slug
+
+	uploadedPageID, _ := getUploadedCTFdPage(pageConfig.Page.Slug)
+	if uploadedPageID == "" || uploadedPageID == "0" {
+		action.Action = "create"
+		return action
+	}
+	action.ID, _ = strconv.Atoi(uploadedPageID)
+
+	live, err := client.GetPage(uploadedPageID)
+	if err != nil {
+		// Pages are re-created when missing
+		action.Action = "create"
+		return action
+	}
+
+	action.Diffs = diffCTFdPage(&pageConfig.Page, live)
+	action.Action = planActionForDiffs(action.Diffs)
+
+	return action
+}
+
+// A change that only hides a visible object is reported as "hide", any other change as "update"
+func planActionForDiffs(diffs []FieldDiff) string {
+	if len(diffs) == 0 {
+		return "none"
+	}
+
+	if len(diffs) == 1 {
+		diff := diffs[0]
+		if (diff.Field == "state" && diff.Desired == "hidden") || (diff.Field == "enabled" && diff.Desired == false) {
+			return "hide"
+		}
+	}
+
+	return "update"
+}
+
+// Run the plan subcommand, printing the plan as JSON to stdout
+func runPlanCommand() {
+	var plan *Plan
+	var err error
+	if getStateBackend() == "file" {
+		// The running manager holds the lock of the state file, so ask it for the plan instead
+		plan, err = fetchServerPlan()
+		if err != nil {
+			log.Printf("Error getting plan from the running manager, reading the state file directly: %s\n", err)
+		}
+	}
+
+	if plan == nil {
+		initGithubClient()
+		if err := initClusterClient(); err != nil {
+			log.Fatalf("Error initializing cluster client: %s", err)
+		}
+		// Do not migrate state, as the plan must not write anything
+		if err := initStateStores(false); err != nil {
+			log.Fatalf("Error initializing state stores: %s", err)
+		}
+
+		plan, err = computePlan()
+		if err != nil {
+			log.Fatalf("Error computing plan: %s", err)
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(plan); err != nil {
+		log.Fatalf("Error writing plan: %s", err)
+	}
+}
+
+// Get the plan from the manager running in the same pod
+func fetchServerPlan() (*Plan, error) {
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/api/ctfd/plan", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+getPassword())
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	var body struct {
+		Plan *Plan `json:"plan"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Plan == nil {
+		return nil, errors.New("response has no plan")
+	}
+	return body.Plan, nil
+}
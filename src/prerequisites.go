@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Resolve the prerequisite slugs of a challenge to the CTFd requirements of the challenge.
+// Fails if a prerequisite has not been uploaded yet, or if the prerequisites form a cycle.
+func resolveChallengeRequirements(challenge *ChallengeConfig) (*ctfd.Requirements, error) {
+	anonymize := challenge.Challenge.AnonymizePrerequisites
+	requirements := &ctfd.Requirements{
+		Anonymize:     &anonymize,
+		Prerequisites: []int{},
+	}
+
+	if len(challenge.Challenge.Prerequisites) == 0 {
+		return requirements, nil
+	}
+
+	if err := checkPrerequisiteCycle(challenge); err != nil {
+		return nil, err
+	}
+
+	prerequisites, err := resolvePrerequisiteIDs(challenge)
+	if err != nil {
+		return nil, err
+	}
+	requirements.Prerequisites = prerequisites
+
+	return requirements, nil
+}
+
+// Resolve the prerequisite slugs of a challenge to CTFd challenge IDs, through the uploaded challenges
+func resolvePrerequisiteIDs(challenge *ChallengeConfig) ([]int, error) {
+	ids := []int{}
+	for _, slug := range challenge.Challenge.Prerequisites {
+		uploadedChallengeID, _ := getUploadedCTFdChallenge(slug)
+		id, err := strconv.Atoi(uploadedChallengeID)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("prerequisite %s of challenge %s has not been uploaded to CTFd", slug, challenge.Challenge.Slug)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Re-sync the challenges requiring the challenge, so their requirements point at its new CTFd ID after it was re-created.
+// Their configmaps have not changed, so their stored hashes are cleared to make the sync worker apply them again.
+func resyncDependentChallenges(slug string) {
+	configMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return
+	}
+
+	for i := range configMaps {
+		challengeConfig, err := extractChallengeConfigMap(&configMaps[i])
+		if err != nil || !slices.Contains(challengeConfig.Challenge.Prerequisites, slug) {
+			continue
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(&configMaps[i])
+		if err != nil {
+			log.Printf("Error getting key for configmap %s: %s\n", configMaps[i].Name, err)
+			continue
+		}
+		if err := storeConfigmapHash(configMaps[i].Name, ""); err != nil {
+			log.Printf("Error clearing hash for configmap %s: %s\n", configMaps[i].Name, err)
+			continue
+		}
+
+		log.Printf("Re-syncing challenge %s, as its prerequisite %s was re-created\n", challengeConfig.Challenge.Slug, slug)
+		enqueueSyncKey(key)
+	}
+}
+
+// Check that the challenge is not its own prerequisite, directly or through other challenge configmaps
+func checkPrerequisiteCycle(challenge *ChallengeConfig) error {
+	prerequisites := map[string][]string{}
+
+	configMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return err
+	}
+	for i := range configMaps {
+		challengeConfig, err := extractChallengeConfigMap(&configMaps[i])
+		if err != nil {
+			continue
+		}
+		prerequisites[challengeConfig.Challenge.Slug] = challengeConfig.Challenge.Prerequisites
+	}
+	// The challenge being synced may be newer than the listed configmap
+	prerequisites[challenge.Challenge.Slug] = challenge.Challenge.Prerequisites
+
+	_, cyclic := orderByPrerequisites([]string{challenge.Challenge.Slug}, prerequisites)
+	if len(cyclic) > 0 {
+		return fmt.Errorf("challenge %s has cyclic prerequisites: %s", challenge.Challenge.Slug, strings.Join(cyclic, ", "))
+	}
+
+	return nil
+}
+
+// Order the slugs so prerequisites come before the challenges requiring them.
+// Prerequisites outside the given slugs are followed to detect cycles, but not included in the order.
+// Slugs that are part of, or depend on, a cycle are returned as cyclic instead.
+func orderByPrerequisites(slugs []string, prerequisites map[string][]string) (ordered []string, cyclic []string) {
+	const (
+		unvisited = iota
+		visiting
+		done
+		failed
+	)
+	state := map[string]int{}
+	included := map[string]bool{}
+	for _, slug := range slugs {
+		included[slug] = true
+	}
+
+	// Depth-first search, appending each slug after its prerequisites
+	var visit func(slug string) bool
+	visit = func(slug string) bool {
+		switch state[slug] {
+		case visiting, failed:
+			return false
+		case done:
+			return true
+		}
+
+		state[slug] = visiting
+		ok := true
+		for _, prerequisite := range prerequisites[slug] {
+			if !visit(prerequisite) {
+				ok = false
+			}
+		}
+
+		if !ok {
+			state[slug] = failed
+			if included[slug] {
+				cyclic = append(cyclic, slug)
+			}
+			return false
+		}
+
+		state[slug] = done
+		if included[slug] {
+			ordered = append(ordered, slug)
+		}
+		return true
+	}
+
+	for _, slug := range slugs {
+		visit(slug)
+	}
+
+	slices.Sort(cyclic)
+	return ordered, cyclic
+}
+
+// Order challenges so prerequisites are synced first. Challenges with cyclic prerequisites are returned separately.
+func orderChallengesByPrerequisites(challenges []*ChallengeConfig) (ordered []*ChallengeConfig, cyclic []*ChallengeConfig) {
+	bySlug := map[string]*ChallengeConfig{}
+	prerequisites := map[string][]string{}
+	slugs := []string{}
+	for _, challenge := range challenges {
+		bySlug[challenge.Challenge.Slug] = challenge
+		prerequisites[challenge.Challenge.Slug] = challenge.Challenge.Prerequisites
+		slugs = append(slugs, challenge.Challenge.Slug)
+	}
+
+	orderedSlugs, cyclicSlugs := orderByPrerequisites(slugs, prerequisites)
+	for _, slug := range orderedSlugs {
+		ordered = append(ordered, bySlug[slug])
+	}
+	for _, slug := range cyclicSlugs {
+		cyclic = append(cyclic, bySlug[slug])
+	}
+
+	return ordered, cyclic
+}
+
+// Order challenge configmaps so prerequisites are synced first.
+// Configmaps that can not be parsed, or have cyclic prerequisites, are placed last, so their sync reports the error.
+func orderChallengeConfigMaps(configMaps []corev1.ConfigMap) []corev1.ConfigMap {
+	challenges := []*ChallengeConfig{}
+	bySlug := map[string]corev1.ConfigMap{}
+	invalid := []corev1.ConfigMap{}
+	for _, configMap := range configMaps {
+		challengeConfig, err := extractChallengeConfigMap(&configMap)
+		if err != nil {
+			invalid = append(invalid, configMap)
+			continue
+		}
+		if _, exists := bySlug[challengeConfig.Challenge.Slug]; exists {
+			invalid = append(invalid, configMap) // Duplicate slug
+			continue
+		}
+		challenges = append(challenges, challengeConfig)
+		bySlug[challengeConfig.Challenge.Slug] = configMap
+	}
+
+	ordered, cyclic := orderChallengesByPrerequisites(challenges)
+
+	result := []corev1.ConfigMap{}
+	for _, challenge := range append(ordered, cyclic...) {
+		result = append(result, bySlug[challenge.Challenge.Slug])
+	}
+	return append(result, invalid...)
+}
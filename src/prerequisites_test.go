@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOrderByPrerequisites(t *testing.T) {
+	tests := []struct {
+		name          string
+		slugs         []string
+		prerequisites map[string][]string
+		ordered       []string
+		cyclic        []string
+	}{
+		{
+			name:    "no prerequisites",
+			slugs:   []string{"a", "b"},
+			ordered: []string{"a", "b"},
+		},
+		{
+			name:          "prerequisites first",
+			slugs:         []string{"c", "b", "a"},
+			prerequisites: map[string][]string{"c": {"b"}, "b": {"a"}},
+			ordered:       []string{"a", "b", "c"},
+		},
+		{
+			name:          "shared prerequisite",
+			slugs:         []string{"b", "c", "a"},
+			prerequisites: map[string][]string{"b": {"a"}, "c": {"a"}},
+			ordered:       []string{"a", "b", "c"},
+		},
+		{
+			name:          "prerequisite outside the slugs",
+			slugs:         []string{"b"},
+			prerequisites: map[string][]string{"b": {"a"}},
+			ordered:       []string{"b"},
+		},
+		{
+			name:          "self prerequisite",
+			slugs:         []string{"a", "b"},
+			prerequisites: map[string][]string{"a": {"a"}},
+			ordered:       []string{"b"},
+			cyclic:        []string{"a"},
+		},
+		{
+			name:          "cycle",
+			slugs:         []string{"a", "b", "c"},
+			prerequisites: map[string][]string{"a": {"b"}, "b": {"a"}},
+			ordered:       []string{"c"},
+			cyclic:        []string{"a", "b"},
+		},
+		{
+			name:          "depends on a cycle",
+			slugs:         []string{"c", "a", "b"},
+			prerequisites: map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"a"}},
+			cyclic:        []string{"a", "b", "c"},
+		},
+		{
+			name:          "cycle outside the slugs",
+			slugs:         []string{"c"},
+			prerequisites: map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"a"}},
+			cyclic:        []string{"c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ordered, cyclic := orderByPrerequisites(test.slugs, test.prerequisites)
+			if !slices.Equal(ordered, test.ordered) {
+				t.Errorf("ordered = %v, want %v", ordered, test.ordered)
+			}
+			if !slices.Equal(cyclic, test.cyclic) {
+				t.Errorf("cyclic = %v, want %v", cyclic, test.cyclic)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+)
+
+// Where the difficulty of a challenge is published in CTFd
+const (
+	DifficultyPublishingNone        = "none"
+	DifficultyPublishingTag         = "tag"
+	DifficultyPublishingDescription = "description"
+	DifficultyPublishingTopic       = "topic"
+)
+
+// Where the author of a challenge is published in CTFd
+const (
+	AuthorPublishingAttribution = "attribution"
+	AuthorPublishingDescription = "description"
+	AuthorPublishingNone        = "none"
+)
+
+// Check that the publishing options of the mapping map are valid
+func validatePublishing(mappingMap MappingMap) error {
+	switch mappingMap.DifficultyPublishing {
+	case "", DifficultyPublishingNone, DifficultyPublishingTag, DifficultyPublishingDescription, DifficultyPublishingTopic:
+	default:
+		return fmt.Errorf("invalid difficulty-publishing %q, valid values are: none, tag, description, topic", mappingMap.DifficultyPublishing)
+	}
+
+	switch mappingMap.AuthorPublishing {
+	case "", AuthorPublishingAttribution, AuthorPublishingDescription, AuthorPublishingNone:
+	default:
+		return fmt.Errorf("invalid author-publishing %q, valid values are: attribution, description, none", mappingMap.AuthorPublishing)
+	}
+
+	return nil
+}
+
+// Get where the difficulty is published, defaulting to not publishing it
+func getDifficultyPublishing(mappingMap MappingMap) string {
+	if mappingMap.DifficultyPublishing == "" {
+		return DifficultyPublishingNone
+	}
+	return mappingMap.DifficultyPublishing
+}
+
+// Get where the author is published, defaulting to the description, as older CTFd versions do not support the attribution field
+func getAuthorPublishing(mappingMap MappingMap) string {
+	if mappingMap.AuthorPublishing == "" {
+		return AuthorPublishingDescription
+	}
+	return mappingMap.AuthorPublishing
+}
+
+// Get the difficulty name to publish, or an empty string if the difficulty is not published in the given way
+func getPublishedDifficulty(challenge *ChallengeConfig, mappingMap MappingMap, publishing string) string {
+	if challenge.Challenge.Difficulty == "" || getDifficultyPublishing(mappingMap) != publishing {
+		return ""
+	}
+	return getDifficultyName(challenge, mappingMap)
+}
+
+// Add the difficulty and author to the description, if they are published there
+func publishInDescription(description string, challenge *ChallengeConfig, mappingMap MappingMap) string {
+	if difficulty := getPublishedDifficulty(challenge, mappingMap, DifficultyPublishingDescription); difficulty != "" {
+		description = "**Difficulty:** " + difficulty + "\n\n" + description
+	}
+
+	if challenge.Challenge.Author != "" && getAuthorPublishing(mappingMap) == AuthorPublishingDescription {
+		description = description + "\n\n---\n\n*Author: " + challenge.Challenge.Author + "*"
+	}
+
+	return description
+}
+
+// Get the CTFd attribution of the challenge
+func getChallengeAttribution(challenge *ChallengeConfig, mappingMap MappingMap) string {
+	if getAuthorPublishing(mappingMap) != AuthorPublishingAttribution {
+		return ""
+	}
+	return challenge.Challenge.Author
+}
+
+// Get the attribution sent to CTFd, or nil unless the author is published as attribution, as older CTFd versions do not support the field
+func getAttributionParam(rendered *RenderedChallenge, mappingMap MappingMap) *string {
+	if getAuthorPublishing(mappingMap) != AuthorPublishingAttribution {
+		return nil
+	}
+	return &rendered.Attribution
+}
+
+// Get the tags of the challenge, including the difficulty if it is published as a tag
+func getChallengeTags(challenge *ChallengeConfig, mappingMap MappingMap) []string {
+	tags := []string{}
+	for _, tag := range challenge.Challenge.Tags {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	if difficulty := getPublishedDifficulty(challenge, mappingMap, DifficultyPublishingTag); difficulty != "" {
+		tags = append(tags, difficulty)
+	}
+
+	return tags
+}
+
+// Get the topics of the challenge. Only the difficulty is published as a topic.
+func getChallengeTopics(challenge *ChallengeConfig, mappingMap MappingMap) []string {
+	topics := []string{}
+	if difficulty := getPublishedDifficulty(challenge, mappingMap, DifficultyPublishingTopic); difficulty != "" {
+		topics = append(topics, difficulty)
+	}
+	return topics
+}
+
+// Sync the topics of a challenge to CTFd, replacing the existing topics
+func syncCTFdChallengeTopics(challengeID int, challenge *ChallengeConfig, mappingMap MappingMap, client *ctfd.Client) error {
+	existing, err := client.GetChallengeTopics(challengeID)
+	if err != nil {
+		log.Printf("Error getting challenge topics: %s\n", err)
+		return err
+	}
+
+	desired := getChallengeTopics(challenge, mappingMap)
+
+	liveTopics := []string{}
+	for _, topic := range existing {
+		liveTopics = append(liveTopics, topic.Value)
+	}
+	if equalStringSets(liveTopics, desired) {
+		return nil
+	}
+
+	for _, topic := range existing {
+		err := client.DeleteTopic(&ctfd.DeleteTopicArgs{
+			ID:   strconv.Itoa(topic.ID),
+			Type: "challenge",
+		})
+		if err != nil {
+			log.Printf("Error deleting topic %s: %s\n", topic.Value, err)
+			return err
+		}
+	}
+
+	for _, topic := range desired {
+		_, err := client.PostTopics(&ctfd.PostTopicsParams{
+			Challenge: challengeID,
+			Type:      "challenge",
+			Value:     topic,
+		})
+		if err != nil {
+			log.Printf("Error uploading topic %s: %s\n", topic, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestAuthorPublishing(t *testing.T) {
+	challenge := &ChallengeConfig{Challenge: Challenge{Author: "someone"}}
+
+	tests := []struct {
+		name        string
+		publishing  string
+		description string
+		attribution *string
+	}{
+		{
+			name:        "unset",
+			description: "Body\n\n---\n\n*Author: someone*",
+		},
+		{
+			name:        "attribution",
+			publishing:  AuthorPublishingAttribution,
+			description: "Body",
+			attribution: &challenge.Challenge.Author,
+		},
+		{
+			name:        "description",
+			publishing:  AuthorPublishingDescription,
+			description: "Body\n\n---\n\n*Author: someone*",
+		},
+		{
+			name:        "none",
+			publishing:  AuthorPublishingNone,
+			description: "Body",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mappingMap := MappingMap{AuthorPublishing: test.publishing}
+
+			if description := publishInDescription("Body", challenge, mappingMap); description != test.description {
+				t.Errorf("description = %q, want %q", description, test.description)
+			}
+
+			rendered := &RenderedChallenge{Attribution: getChallengeAttribution(challenge, mappingMap)}
+			attribution := getAttributionParam(rendered, mappingMap)
+			if (attribution == nil) != (test.attribution == nil) || (attribution != nil && *attribution != *test.attribution) {
+				t.Errorf("attribution = %v, want %v", attribution, test.attribution)
+			}
+		})
+	}
+}
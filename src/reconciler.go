@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type ReconcileFix struct {
+	Kind      string   `json:"kind"` // "challenge" or "page"
+	ConfigMap string   `json:"configmap"`
+	Slug      string   `json:"slug"`
+	ID        int      `json:"id,omitempty"`
+	Fields    []string `json:"fields,omitempty"`  // Fields that differed from CTFd
+	Adopted   []string `json:"adopted,omitempty"` // Fields adopted from CTFd, see drift policies
+	Error     string   `json:"error,omitempty"`
+}
+
+type ReconcileReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Checked    int            `json:"checked"`
+	Fixed      []ReconcileFix `json:"fixed"`
+	Errors     []ReconcileFix `json:"errors"`
+}
+
+var reconcileMutex sync.Mutex
+var lastReconcileReport atomic.Pointer[ReconcileReport]
+
+var errReconcileRunning = errors.New("reconciliation already running")
+
+func initBackgroundReconciler() {
+	interval := getReconcileInterval()
+	if interval == 0 {
+		log.Println("Periodic reconciliation disabled (RECONCILE_INTERVAL=0)")
+		return
+	}
+
+	log.Printf("Initializing background reconciler with interval %s...\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := runReconciliation(); err != nil {
+			log.Printf("Error running reconciliation: %s\n", err)
+		}
+	}
+}
+
+// Compare every challenge and page ConfigMap against CTFd, and re-apply the ones that differ
+func runReconciliation() (*ReconcileReport, error) {
+	if !reconcileMutex.TryLock() {
+		return nil, errReconcileRunning
+	}
+	defer reconcileMutex.Unlock()
+
+	log.Println("Starting reconciliation between ConfigMaps and CTFd...")
+
+	report := &ReconcileReport{
+		StartedAt: time.Now(),
+		Fixed:     []ReconcileFix{},
+		Errors:    []ReconcileFix{},
+	}
+
+	client, err := getCTFdClient()
+	if err != nil {
+		log.Printf("Error getting CTFd client: %s\n", err)
+		return nil, err
+	}
+
+	challengeConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "challenge-config"})
+	if err != nil {
+		log.Printf("Error listing challenge configmaps: %s\n", err)
+		return nil, err
+	}
+
+	pageConfigMaps, err := listConfigMapsByLabel(getNamespace(), map[string]string{watchedConfigMaps: "page-config"})
+	if err != nil {
+		log.Printf("Error listing page configmaps: %s\n", err)
+		return nil, err
+	}
+
+	mappingMap, err := getMappingMap(getNamespace())
+	if err != nil {
+		log.Printf("Error getting mapping map: %s\n", err)
+		return nil, err
+	}
+
+	// Reconcile prerequisites before the challenges requiring them
+	challengeConfigMaps = orderChallengeConfigMaps(challengeConfigMaps)
+	for i := range challengeConfigMaps {
+		report.Checked++
+		fix := reconcileChallenge(&challengeConfigMaps[i], client, mappingMap)
+		report.add(fix)
+	}
+
+	for i := range pageConfigMaps {
+		report.Checked++
+		fix := reconcilePage(&pageConfigMaps[i], client)
+		report.add(fix)
+	}
+
+	report.FinishedAt = time.Now()
+	lastReconcileReport.Store(report)
+
+	log.Printf("Reconciliation finished: checked %d, fixed %d, errors %d\n", report.Checked, len(report.Fixed), len(report.Errors))
+
+	return report, nil
+}
+
+func (report *ReconcileReport) add(fix *ReconcileFix) {
+	if fix == nil {
+		return
+	}
+
+	if fix.Error != "" {
+		report.Errors = append(report.Errors, *fix)
+		return
+	}
+	report.Fixed = append(report.Fixed, *fix)
+}
+
+func reconcileChallenge(configMap *corev1.ConfigMap, client *ctfd.Client, mappingMap MappingMap) *ReconcileFix {
+	fix := &ReconcileFix{
+		Kind:      "challenge",
+		ConfigMap: configMap.Name,
+	}
+
+	challengeConfig, err := extractChallengeConfigMap(configMap)
+	if err != nil {
+		fix.Error = err.Error()
+		return fix
+	}
+	fix.Slug = challengeConfig.Challenge.Slug
+
+	// Find the live challenge in CTFd, if it has been uploaded
+	var live *ctfd.Challenge
+	uploadedChallengeID, _ := getUploadedCTFdChallenge(challengeConfig.Challenge.Slug)
+	if id, err := strconv.Atoi(uploadedChallengeID); err == nil && id != 0 {
+		live, err = client.GetChallenge(id)
+		if err != nil {
+			log.Printf("Challenge %s (%d) not found in CTFd: %s\n", challengeConfig.Challenge.Slug, id, err)
+			live = nil
+		}
+	}
+
+	if live == nil {
+		fix.Fields = []string{"missing"}
+	} else {
+		diffs, err := diffCTFdChallenge(challengeConfig, live, client, mappingMap)
+		if err != nil {
+			fix.Error = err.Error()
+			return fix
+		}
+
+		// Apply the drift policy of the challenge
+		switch getChallengeDriftPolicy(configMap) {
+		case DriftPolicyIgnore:
+			if len(diffs) > 0 {
+				log.Printf("Challenge %s differs from CTFd (%v), ignoring due to drift policy\n", challengeConfig.Challenge.Slug, diffFieldNames(diffs))
+			}
+			return nil
+		case DriftPolicyAdopt:
+			remaining, err := adoptChallengeDrift(challengeConfig.Challenge.Slug, diffs)
+			if err != nil {
+				fix.Error = err.Error()
+				return fix
+			}
+			for _, diff := range diffs {
+				if adoptableFields[diff.Field] {
+					fix.Adopted = append(fix.Adopted, diff.Field)
+				}
+			}
+			diffs = remaining
+		}
+		fix.Fields = diffFieldNames(diffs)
+	}
+
+	if len(fix.Fields) == 0 {
+		if len(fix.Adopted) > 0 {
+			return fix
+		}
+		return nil
+	}
+
+	log.Printf("Challenge %s differs from CTFd (%v), re-applying\n", challengeConfig.Challenge.Slug, fix.Fields)
+	id, err := updateOrCreateCTFdChallenge(challengeConfig)
+	recordReconciledSync(configMap, id, err)
+	if err != nil {
+		fix.Error = err.Error()
+		return fix
+	}
+	fix.ID = id
+
+	return fix
+}
+
+func reconcilePage(configMap *corev1.ConfigMap, client *ctfd.Client) *ReconcileFix {
+	fix := &ReconcileFix{
+		Kind:      "page",
+		ConfigMap: configMap.Name,
+	}
+
+	pageConfig, err := extractPageConfigMap(configMap)
+	if err != nil {
+		fix.Error = err.Error()
+		return fix
+	}
+	fix.Slug = pageConfig.Page.Slug
+
+	// Find the live page in CTFd, if it has been uploaded
+	var live *ctfd.Page
+	uploadedPageID, _ := getUploadedCTFdPage(pageConfig.Page.Slug)
+	if uploadedPageID != "" && uploadedPageID != "0" {
+		live, err = client.GetPage(uploadedPageID)
+		if err != nil {
+			log.Printf("Page %s (%s) not found in CTFd: %s\n", pageConfig.Page.Slug, uploadedPageID, err)
+			live = nil
+		}
+	}
+
+	if live == nil {
+		fix.Fields = []string{"missing"}
+	} else {
+		fix.Fields = diffFieldNames(diffCTFdPage(&pageConfig.Page, live))
+	}
+
+	if len(fix.Fields) == 0 {
+		return nil
+	}
+
+	log.Printf("Page %s differs from CTFd (%v), re-applying\n", pageConfig.Page.Slug, fix.Fields)
+	id, err := uploadOrUpdateCTFdPage(&pageConfig.Page)
+	recordReconciledSync(configMap, id, err)
+	if err != nil {
+		fix.Error = err.Error()
+		return fix
+	}
+	fix.ID = id
+
+	return fix
+}
+
+// Record the outcome of a reconciled configmap, and store its hash so the watcher does not re-deploy it
+func recordReconciledSync(configMap *corev1.ConfigMap, id int, syncErr error) {
+	hash, err := getHashForConfigMap(configMap)
+	if err != nil {
+		log.Printf("Error generating hash for configmap %s: %v\n", configMap.Name, err)
+		return
+	}
+
+	recordConfigMapSync(configMap, id, hash, syncErr)
+	if syncErr != nil {
+		return
+	}
+
+	if err := storeConfigmapHash(configMap.Name, hash); err != nil {
+		log.Printf("Error storing hash for configmap %s: %v\n", configMap.Name, err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Backend holding the state stores of the manager
+type StateBackend interface {
+	// Get the state store with the given name
+	Store(name string) (StateStore, error)
+}
+
+// Names of the state stores used by the manager
+var stateStoreNames = []string{
+	CTFDCHALLENGESCONFIGMAP,
+	CTFDPAGESCONFIGMAP,
+	CONFIGMAPHASHSETCONFIGMAP,
+	CHALLENGEOVERRIDESCONFIGMAP,
+	CHALLENGECOMMITSCONFIGMAP,
+}
+
+func newStateBackend(name string) (StateBackend, error) {
+	switch name {
+	case "configmap":
+		return &configMapStateBackend{namespace: getNamespace()}, nil
+	case "sharded-configmap":
+		return &shardedConfigMapStateBackend{namespace: getNamespace(), shards: getStateShards()}, nil
+	case "custom-resource":
+		return newCustomResourceStateBackend(getNamespace())
+	case "file":
+		return newFileStateBackend(getStateFilePath())
+	default:
+		return nil, fmt.Errorf("unknown state backend %q, valid values are: configmap, sharded-configmap, custom-resource, file", name)
+	}
+}
+
+// Initialize the state stores, migrating state from STATE_MIGRATE_FROM if migrate is set
+func initStateStores(migrate bool) error {
+	log.Printf("Initializing %s state backend...\n", getStateBackend())
+
+	backend, err := newStateBackend(getStateBackend())
+	if err != nil {
+		return err
+	}
+
+	// Migrate state from the previous backend, if requested
+	if migrateFrom := getStateMigrateFrom(); migrate && migrateFrom != "" && migrateFrom != getStateBackend() {
+		source, err := newStateBackend(migrateFrom)
+		if err != nil {
+			return err
+		}
+		if err := migrateState(source, backend); err != nil {
+			return fmt.Errorf("error migrating state from %s: %w", migrateFrom, err)
+		}
+	}
+
+	if challengeStore, err = backend.Store(CTFDCHALLENGESCONFIGMAP); err != nil {
+		return err
+	}
+	if pageStore, err = backend.Store(CTFDPAGESCONFIGMAP); err != nil {
+		return err
+	}
+	if hashStore, err = backend.Store(CONFIGMAPHASHSETCONFIGMAP); err != nil {
+		return err
+	}
+	if overrideStore, err = backend.Store(CHALLENGEOVERRIDESCONFIGMAP); err != nil {
+		return err
+	}
+	if commitStore, err = backend.Store(CHALLENGECOMMITSCONFIGMAP); err != nil {
+		return err
+	}
+
+	log.Println("State backend initialized successfully")
+
+	return nil
+}
+
+// Copy all state from the source backend to the target backend.
+// Stores that already contain state in the target are skipped, so the migration only runs once.
+func migrateState(source StateBackend, target StateBackend) error {
+	for _, name := range stateStoreNames {
+		targetStore, err := target.Store(name)
+		if err != nil {
+			return err
+		}
+		existing, err := targetStore.List()
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			log.Printf("State store %s already contains state, skipping migration\n", name)
+			continue
+		}
+
+		sourceStore, err := source.Store(name)
+		if err != nil {
+			return err
+		}
+		data, err := sourceStore.List()
+		if apierrors.IsNotFound(err) {
+			// Stores added in later versions may not exist in the old backend
+			log.Printf("State store %s does not exist in the source backend, skipping migration\n", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		if err := targetStore.SetMany(data); err != nil {
+			return err
+		}
+		log.Printf("Migrated %d entries of state store %s\n", len(data), name)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// Resource of the CTFdManagerState custom resource. See k8s/crd.yml for the definition.
+var stateResource = schema.GroupVersionResource{
+	Group:    "ctfpilot.com",
+	Version:  "v1alpha1",
+	Resource: "ctfdmanagerstates",
+}
+
+// State backend that stores each key as a separate CTFdManagerState custom resource
+type customResourceStateBackend struct {
+	namespace string
+	client    dynamic.Interface
+}
+
+func newCustomResourceStateBackend(namespace string) (*customResourceStateBackend, error) {
+	if clusterConfig == nil {
+		return nil, errors.New("Kubernetes client not initialized")
+	}
+
+	client, err := dynamic.NewForConfig(clusterConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &customResourceStateBackend{
+		namespace: namespace,
+		client:    client,
+	}, nil
+}
+
+func (backend *customResourceStateBackend) Store(name string) (StateStore, error) {
+	return &customResourceStateStore{
+		name:     name,
+		resource: backend.client.Resource(stateResource).Namespace(backend.namespace),
+	}, nil
+}
+
+type customResourceStateStore struct {
+	name     string
+	resource dynamic.ResourceInterface
+}
+
+// Keys may contain characters not allowed in resource names, so the name is derived from a hash of the key
+func (store *customResourceStateStore) resourceName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return store.name + "-" + hex.EncodeToString(hash[:8])
+}
+
+func (store *customResourceStateStore) Get(key string) (string, bool, error) {
+	object, err := store.resource.Get(context.TODO(), store.resourceName(key), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	value, _, err := unstructured.NestedString(object.Object, "spec", "value")
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (store *customResourceStateStore) List() (map[string]string, error) {
+	objects, err := store.resource.List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.Set{stateStoreLabel: store.name}.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	for _, object := range objects.Items {
+		key, _, err := unstructured.NestedString(object.Object, "spec", "key")
+		if err != nil {
+			return nil, err
+		}
+		value, _, err := unstructured.NestedString(object.Object, "spec", "value")
+		if err != nil {
+			return nil, err
+		}
+		data[key] = value
+	}
+
+	return data, nil
+}
+
+func (store *customResourceStateStore) Set(key string, value string) error {
+	patch, err := json.Marshal(map[string]any{
+		"spec": map[string]string{
+			"value": value,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = store.resource.Patch(context.TODO(), store.resourceName(key), types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	object := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": stateResource.GroupVersion().String(),
+			"kind":       "CTFdManagerState",
+			"metadata": map[string]any{
+				"name": store.resourceName(key),
+				"labels": map[string]any{
+					stateStoreLabel: store.name,
+				},
+			},
+			"spec": map[string]any{
+				"store": store.name,
+				"key":   key,
+				"value": value,
+			},
+		},
+	}
+
+	_, err = store.resource.Create(context.TODO(), object, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Created concurrently, patch it instead
+		_, err = store.resource.Patch(context.TODO(), store.resourceName(key), types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+func (store *customResourceStateStore) SetMany(values map[string]string) error {
+	for key, value := range values {
+		if err := store.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *customResourceStateStore) Delete(key string) error {
+	err := store.resource.Delete(context.TODO(), store.resourceName(key), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
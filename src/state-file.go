@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// State backend that stores each state store as a bucket in a local embedded database file.
+// The file is local to the replica, so it should be placed on a persistent volume.
+type fileStateBackend struct {
+	db *bolt.DB
+}
+
+func newFileStateBackend(path string) (*fileStateBackend, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileStateBackend{db: db}, nil
+}
+
+func (backend *fileStateBackend) Store(name string) (StateStore, error) {
+	err := backend.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileStateStore{
+		db:     backend.db,
+		bucket: []byte(name),
+	}, nil
+}
+
+type fileStateStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+func (store *fileStateStore) Get(key string) (string, bool, error) {
+	var value []byte
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucket)
+		if bucket == nil {
+			return errors.New("state bucket not found")
+		}
+		// Copy the value, as it is only valid during the transaction
+		if data := bucket.Get([]byte(key)); data != nil {
+			value = append([]byte{}, data...)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(value), value != nil, nil
+}
+
+func (store *fileStateStore) List() (map[string]string, error) {
+	data := make(map[string]string)
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucket)
+		if bucket == nil {
+			return errors.New("state bucket not found")
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			data[string(key)] = string(value)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (store *fileStateStore) Set(key string, value string) error {
+	return store.SetMany(map[string]string{key: value})
+}
+
+func (store *fileStateStore) SetMany(values map[string]string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucket)
+		if bucket == nil {
+			return errors.New("state bucket not found")
+		}
+		for key, value := range values {
+			if err := bucket.Put([]byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (store *fileStateStore) Delete(key string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(store.bucket)
+		if bucket == nil {
+			return errors.New("state bucket not found")
+		}
+		return bucket.Delete([]byte(key))
+	})
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const stateStoreLabel = "challenges.ctfpilot.com/state-store"
+
+// State backend that spreads each state store over multiple configmaps, to stay below the configmap size limit.
+// Keys are assigned to shards by hash, so the number of shards must not change without migrating.
+type shardedConfigMapStateBackend struct {
+	namespace string
+	shards    int
+}
+
+func (backend *shardedConfigMapStateBackend) Store(name string) (StateStore, error) {
+	return &shardedConfigMapStateStore{
+		namespace: backend.namespace,
+		name:      name,
+		shards:    backend.shards,
+	}, nil
+}
+
+type shardedConfigMapStateStore struct {
+	namespace string
+	name      string
+	shards    int
+}
+
+func (store *shardedConfigMapStateStore) shardName(key string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return fmt.Sprintf("%s-shard-%d", store.name, hash.Sum32()%uint32(store.shards))
+}
+
+func (store *shardedConfigMapStateStore) Get(key string) (string, bool, error) {
+	configMap, err := clientset.CoreV1().ConfigMaps(store.namespace).Get(context.TODO(), store.shardName(key), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok := configMap.Data[key]
+	return value, ok, nil
+}
+
+func (store *shardedConfigMapStateStore) List() (map[string]string, error) {
+	configMaps, err := listConfigMapsByLabel(store.namespace, map[string]string{stateStoreLabel: store.name})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	for _, configMap := range configMaps {
+		for key, value := range configMap.Data {
+			data[key] = value
+		}
+	}
+
+	return data, nil
+}
+
+func (store *shardedConfigMapStateStore) Set(key string, value string) error {
+	return store.patchShard(store.shardName(key), map[string]*string{key: &value})
+}
+
+func (store *shardedConfigMapStateStore) SetMany(values map[string]string) error {
+	// Group the values by shard, to write each shard once
+	shards := make(map[string]map[string]*string)
+	for key, value := range values {
+		shard := store.shardName(key)
+		if shards[shard] == nil {
+			shards[shard] = make(map[string]*string)
+		}
+		shards[shard][key] = &value
+	}
+
+	for shard, data := range shards {
+		if err := store.patchShard(shard, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *shardedConfigMapStateStore) Delete(key string) error {
+	err := store.patchShard(store.shardName(key), map[string]*string{key: nil})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Merge patch the data of a shard, creating the shard if it does not exist yet
+func (store *shardedConfigMapStateStore) patchShard(shard string, data map[string]*string) error {
+	patch, err := json.Marshal(map[string]any{
+		"data": data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps(store.namespace).Patch(context.TODO(), shard, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	// Create the shard with the data
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shard,
+			Namespace: store.namespace,
+			Labels:    labels.Set{stateStoreLabel: store.name},
+		},
+		Data: make(map[string]string),
+	}
+	for key, value := range data {
+		if value != nil {
+			configMap.Data[key] = *value
+		}
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps(store.namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Created concurrently, patch it instead
+		_, err = clientset.CoreV1().ConfigMaps(store.namespace).Patch(context.TODO(), shard, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		log.Printf("Error writing state shard %s: %s\n", shard, err)
+		return err
+	}
+
+	log.Printf("Created state shard %s\n", shard)
+	return nil
+}
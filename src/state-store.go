@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Key-value store for the sync state of the manager, such as CTFd IDs and configmap hashes
+type StateStore interface {
+	// Get the value of a key, and whether the key exists
+	Get(key string) (string, bool, error)
+	// List all keys and values
+	List() (map[string]string, error)
+	// Set the value of a single key
+	Set(key string, value string) error
+	// Set the values of multiple keys at once
+	SetMany(values map[string]string) error
+	// Remove a key
+	Delete(key string) error
+}
+
+var challengeStore StateStore
+var pageStore StateStore
+var hashStore StateStore
+var overrideStore StateStore
+var commitStore StateStore
+
+// State backend that stores each state store in a single configmap
+type configMapStateBackend struct {
+	namespace string
+}
+
+func (backend *configMapStateBackend) Store(name string) (StateStore, error) {
+	return newConfigMapStateStore(backend.namespace, name), nil
+}
+
+// State store backed by the data of a single configmap.
+// Single keys are written with merge patches, so concurrent writers never overwrite each other's keys.
+type configMapStateStore struct {
+	namespace string
+	name      string
+}
+
+func newConfigMapStateStore(namespace string, name string) *configMapStateStore {
+	return &configMapStateStore{
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (store *configMapStateStore) Get(key string) (string, bool, error) {
+	data, err := store.List()
+	if err != nil {
+		return "", false, err
+	}
+
+	value, ok := data[key]
+	return value, ok, nil
+}
+
+func (store *configMapStateStore) List() (map[string]string, error) {
+	configMap, err := getConfigMap(store.namespace, store.name)
+	if err != nil {
+		return nil, err
+	}
+	if configMap == nil {
+		return nil, errors.New("Configmap not found")
+	}
+
+	if configMap.Data == nil {
+		return make(map[string]string), nil
+	}
+	return configMap.Data, nil
+}
+
+func (store *configMapStateStore) Set(key string, value string) error {
+	return store.patch(map[string]*string{key: &value})
+}
+
+func (store *configMapStateStore) SetMany(values map[string]string) error {
+	data := make(map[string]*string, len(values))
+	for key, value := range values {
+		data[key] = &value
+	}
+	return store.patch(data)
+}
+
+func (store *configMapStateStore) Delete(key string) error {
+	// A null value removes the key in a merge patch
+	return store.patch(map[string]*string{key: nil})
+}
+
+// Merge patch the data of the configmap, creating the configmap if it does not exist yet
+func (store *configMapStateStore) patch(data map[string]*string) error {
+	patch, err := json.Marshal(map[string]any{
+		"data": data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps(store.namespace).Patch(context.TODO(), store.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		if err != nil {
+			log.Printf("Error patching state configmap %s: %s\n", store.name, err)
+		}
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      store.name,
+			Namespace: store.namespace,
+		},
+		Data: make(map[string]string),
+	}
+	for key, value := range data {
+		if value != nil {
+			configMap.Data[key] = *value
+		}
+	}
+	if len(configMap.Data) == 0 {
+		// Only keys were removed, which a missing configmap does not have
+		return nil
+	}
+
+	_, err = clientset.CoreV1().ConfigMaps(store.namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Created concurrently, patch it instead
+		_, err = clientset.CoreV1().ConfigMaps(store.namespace).Patch(context.TODO(), store.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		log.Printf("Error writing state configmap %s: %s\n", store.name, err)
+		return err
+	}
+
+	log.Printf("Created state configmap %s\n", store.name)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type SyncStatus struct {
+	Key         string     `json:"key"`
+	Attempts    int        `json:"attempts"` // Failed attempts since the last successful sync
+	LastError   string     `json:"last_error,omitempty"`
+	LastAttempt time.Time  `json:"last_attempt"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	GaveUp      bool       `json:"gave_up"` // Set when the max attempts have been reached
+}
+
+// Queue of configmap keys (namespace/name) waiting to be synced to CTFd.
+// Keys are deduplicated while queued, and failed syncs are retried with exponential backoff.
+var syncQueue = workqueue.NewTypedRateLimitingQueueWithConfig(
+	workqueue.NewTypedItemExponentialFailureRateLimiter[string](time.Second, 5*time.Minute),
+	workqueue.TypedRateLimitingQueueConfig[string]{Name: "configmaps"},
+)
+
+// Last known state of deleted configmaps, until their deletion has been synced
+var deletedConfigMaps sync.Map
+
+var syncStatusMutex sync.Mutex
+var syncStatuses = map[string]*SyncStatus{}
+
+func runSyncWorker(indexer cache.Indexer) {
+	for processNextSyncItem(indexer) {
+	}
+}
+
+func processNextSyncItem(indexer cache.Indexer) bool {
+	key, shutdown := syncQueue.Get()
+	if shutdown {
+		return false
+	}
+	defer syncQueue.Done(key)
+
+	err := syncConfigMapKey(indexer, key)
+	if err == nil {
+		syncQueue.Forget(key)
+		if _, exists, _ := indexer.GetByKey(key); exists {
+			recordSyncSuccess(key)
+		} else {
+			// The configmap was deleted, so there is nothing left to report
+			removeSyncStatus(key)
+		}
+		return true
+	}
+
+	attempts := recordSyncFailure(key, err)
+	if attempts < getSyncMaxAttempts() {
+		log.Printf("Error syncing configmap %s (attempt %d), retrying: %v\n", key, attempts, err)
+		syncQueue.AddRateLimited(key)
+		return true
+	}
+
+	log.Printf("Error syncing configmap %s, giving up after %d attempts: %v\n", key, attempts, err)
+	syncQueue.Forget(key)
+	markSyncGaveUp(key)
+	return true
+}
+
+// Queue a configmap key for a new event from the informer.
+// The failed attempts are reset, so a configmap that was given up on is retried again with the full number of attempts.
+func enqueueSyncKey(key string) {
+	syncStatusMutex.Lock()
+	if status, ok := syncStatuses[key]; ok {
+		status.Attempts = 0
+		status.GaveUp = false
+	}
+	syncStatusMutex.Unlock()
+
+	syncQueue.Forget(key)
+	syncQueue.Add(key)
+}
+
+func recordSyncSuccess(key string) {
+	syncStatusMutex.Lock()
+	defer syncStatusMutex.Unlock()
+
+	now := time.Now()
+	syncStatuses[key] = &SyncStatus{
+		Key:         key,
+		LastAttempt: now,
+		LastSuccess: &now,
+	}
+}
+
+func recordSyncFailure(key string, err error) int {
+	syncStatusMutex.Lock()
+	defer syncStatusMutex.Unlock()
+
+	status, ok := syncStatuses[key]
+	if !ok {
+		status = &SyncStatus{Key: key}
+		syncStatuses[key] = status
+	}
+	status.Attempts++
+	status.LastError = err.Error()
+	status.LastAttempt = time.Now()
+	status.GaveUp = false
+
+	return status.Attempts
+}
+
+func markSyncGaveUp(key string) {
+	syncStatusMutex.Lock()
+	defer syncStatusMutex.Unlock()
+
+	if status, ok := syncStatuses[key]; ok {
+		status.GaveUp = true
+	}
+}
+
+func removeSyncStatus(key string) {
+	syncStatusMutex.Lock()
+	defer syncStatusMutex.Unlock()
+
+	delete(syncStatuses, key)
+}
+
+func getSyncStatuses() []SyncStatus {
+	syncStatusMutex.Lock()
+	defer syncStatusMutex.Unlock()
+
+	statuses := make([]SyncStatus, 0, len(syncStatuses))
+	for _, status := range syncStatuses {
+		statuses = append(statuses, *status)
+	}
+	slices.SortFunc(statuses, func(a, b SyncStatus) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return statuses
+}
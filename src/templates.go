@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	ctfd "github.com/ctfer-io/go-ctfd/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const TEMPLATEVARSCONFIGMAP = "template-vars"
+
+// Data available to the description and connection templates of a challenge
+type TemplateData struct {
+	Vars        map[string]string // Event variables from the template-vars configmap
+	Slug        string
+	Name        string
+	Author      string
+	Category    string // Mapped category name
+	Difficulty  string // Mapped difficulty name
+	Points      int
+	FrontMatter map[string]any // Front-matter of the description
+}
+
+// Get the event variables from the template-vars configmap. The configmap is optional.
+func getTemplateVars(namespace string) (map[string]string, error) {
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), TEMPLATEVARSCONFIGMAP, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+	for key, value := range configMap.Data {
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// Check if the challenge description and connection are rendered as templates.
+// Templating is opt-in, as existing descriptions may contain "{{", such as template injection payloads.
+func isTemplatingEnabled(challenge *ChallengeConfig) bool {
+	return challenge.Challenge.Templating != nil && *challenge.Challenge.Templating
+}
+
+// Render a template of a challenge.
+// files are the files of the challenge in CTFd, used by the file helper. If nil, the files have not been uploaded yet, and file links are not resolved.
+func renderChallengeTemplate(name string, text string, data TemplateData, files []*ctfd.File) (string, error) {
+	funcs := template.FuncMap{
+		// Link to a file of the challenge in CTFd
+		"file": func(fileName string) (string, error) {
+			if files == nil {
+				return "/files/" + fileName, nil
+			}
+			for _, file := range files {
+				if path.Base(file.Location) == fileName {
+					return "/files/" + file.Location, nil
+				}
+			}
+			return "", fmt.Errorf("file %q not found in the files of the challenge", fileName)
+		},
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s template: %w", name, err)
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("error rendering %s template: %w", name, err)
+	}
+
+	return builder.String(), nil
+}
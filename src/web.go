@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 )
 
 // ---------
@@ -43,6 +44,20 @@ func middleware(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// Reject mutating requests on replicas that are not the leader, as only the leader may write to CTFd
+func leaderMiddleware(w http.ResponseWriter, r *http.Request) error {
+	if isLeader() {
+		return nil
+	}
+
+	leader := getLeader()
+	if leader != "" {
+		w.Header().Set("X-Leader", leader)
+	}
+	errorResponse(w, r, http.StatusServiceUnavailable, "This replica is not the leader. Send the request to the leader: "+leader)
+	return errNotLeader
+}
+
 // ---------
 // Challenges
 // ---------
@@ -96,9 +111,17 @@ func getChallengeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get the commit the challenge files were last synced at, from the sync state
+	syncedCommit, error := getSyncedChallengeCommit(challengeConfig.Challenge.Slug)
+	if error != nil {
+		errorResponse(w, r, http.StatusInternalServerError, "Error getting synced commit")
+		return
+	}
+	syncedCommitJson, _ := json.Marshal(syncedCommit)
+
 	// Print challenge in json
 	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, "{\"config\":%s}\n", jsonFormatChallengeConfig(challengeConfig))
+	fmt.Fprintf(w, "{\"config\":%s,\"synced_commit\":%s}\n", jsonFormatChallengeConfig(challengeConfig), syncedCommitJson)
 }
 
 func getChallengeFilesDirContentHandler(w http.ResponseWriter, r *http.Request) {
@@ -125,7 +148,13 @@ func getChallengeFilesDirContentHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Get the contents of the directory
-	contents, error := getGithubDirContents(getGithubRepo(), getGithubBranch(), filesDirPath(challengeConfig))
+	source, error := challengeFileSource(challengeConfig)
+	if error != nil {
+		log.Printf("Error getting file source: %s\n", error)
+		errorResponse(w, r, http.StatusInternalServerError, "Error getting file source")
+		return
+	}
+	contents, error := source.ListFiles(challengeRef(challengeConfig), filesDirPath(challengeConfig))
 
 	if error != nil {
 		log.Printf("Error getting directory contents (err): %s\n", error)
@@ -182,8 +211,13 @@ func getChallengeFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all the files in the directory
-	path := filesDirPath(challengeConfig)
-	contents, error := getGithubDirContents(getGithubRepo(), getGithubBranch(), path)
+	source, error := challengeFileSource(challengeConfig)
+	if error != nil {
+		log.Printf("Error getting file source: %s\n", error)
+		errorResponse(w, r, http.StatusInternalServerError, "Error getting file source")
+		return
+	}
+	contents, error := source.ListFiles(challengeRef(challengeConfig), filesDirPath(challengeConfig))
 
 	if error != nil {
 		log.Printf("Error getting directory contents (err): %s\n", error)
@@ -197,22 +231,21 @@ func getChallengeFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the file exists in the directory
-	fileExists := false
+	filePath := ""
 	for _, content := range contents {
-		if content.GetName() == file {
-			fileExists = true
+		if content.Name == file {
+			filePath = content.Path
 			break
 		}
 	}
-	if !fileExists {
+	if filePath == "" {
 		log.Printf("File not found in directory. Invalid file name provided")
 		errorResponse(w, r, http.StatusNotFound, "File not found")
 		return
 	}
 
 	// Get the contents of the file
-	path = path + "/" + file
-	data, error := getGithubFileBytes(getGithubRepo(), getGithubBranch(), path)
+	bytes, error := source.ReadFile(challengeRef(challengeConfig), filePath)
 
 	if error != nil {
 		log.Printf("Error getting file contents: %s\n", error)
@@ -220,14 +253,6 @@ func getChallengeFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if data == nil {
-		log.Printf("Error getting file contents (bytes): %s\n", error)
-		errorResponse(w, r, http.StatusNotFound, "File not found")
-		return
-	}
-
-	bytes := []byte(*data)
-
 	// Set the content type based on the file extension
 	contentType := http.DetectContentType(bytes)
 	w.Header().Set("Content-Type", contentType)
@@ -257,6 +282,12 @@ func postSetupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the leader may write to CTFd
+	if err := leaderMiddleware(w, r); err != nil {
+		log.Printf("Leader middleware error: %s\n", err)
+		return
+	}
+
 	postSetupCTFd(w, r)
 }
 
@@ -267,6 +298,12 @@ func postUploadChallengesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the leader may write to CTFd
+	if err := leaderMiddleware(w, r); err != nil {
+		log.Printf("Leader middleware error: %s\n", err)
+		return
+	}
+
 	// Get challenges from the cluster
 	challenges, error := getConfigMapsByLabel(getNamespace(), map[string]string{"challenges.ctfpilot.com/configmap": "challenge-config"})
 
@@ -276,7 +313,8 @@ func postUploadChallengesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Upload each challenge to CTFd
+	// Get each challenge config
+	challengeConfigs := []*ChallengeConfig{}
 	for _, config := range challenges {
 		challengeConfig, error := getChallengeConfigMapByLabel(getNamespace(), config, map[string]string{"challenges.ctfpilot.com/configmap": "challenge-config"})
 
@@ -285,11 +323,27 @@ func postUploadChallengesHandler(w http.ResponseWriter, r *http.Request) {
 			errorResponse(w, r, http.StatusNotFound, "Challenge not found")
 			return
 		}
+		challengeConfigs = append(challengeConfigs, challengeConfig)
+	}
+
+	// Upload prerequisites before the challenges requiring them
+	ordered, cyclic := orderChallengesByPrerequisites(challengeConfigs)
+	if len(cyclic) > 0 {
+		slugs := []string{}
+		for _, challengeConfig := range cyclic {
+			slugs = append(slugs, challengeConfig.Challenge.Slug)
+		}
+		log.Printf("Challenges with cyclic prerequisites: %v\n", slugs)
+		errorResponse(w, r, http.StatusUnprocessableEntity, "Challenges have cyclic prerequisites: "+strings.Join(slugs, ", "))
+		return
+	}
 
+	// Upload each challenge to CTFd
+	for _, challengeConfig := range ordered {
 		id, err := updateOrCreateCTFdChallenge(challengeConfig)
 		if err != nil {
 			log.Printf("Error uploading challenge: %s\n", err)
-			errorResponse(w, r, http.StatusInternalServerError, "Error uploading challenge")
+			errorResponse(w, r, http.StatusInternalServerError, "Error uploading challenge "+challengeConfig.Challenge.Slug+": "+err.Error())
 			return
 		}
 		log.Printf("Uploaded challenge %d", id)
@@ -353,3 +407,180 @@ func getCTFdUploadedChallengesHandler(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Fprintf(w, "{\"uploaded_challenges\":%s}\n", string(jsonData))
 }
+
+func reconcileHandler(w http.ResponseWriter, r *http.Request) {
+	// Authorize the request
+	if err := middleware(w, r); err != nil {
+		log.Printf("Middleware error: %s\n", err)
+		return
+	}
+
+	var report *ReconcileReport
+	switch r.Method {
+	case http.MethodGet:
+		report = lastReconcileReport.Load()
+		if report == nil {
+			errorResponse(w, r, http.StatusNotFound, "No reconciliation has run yet")
+			return
+		}
+	case http.MethodPost:
+		// Only the leader may write to CTFd
+		if err := leaderMiddleware(w, r); err != nil {
+			log.Printf("Leader middleware error: %s\n", err)
+			return
+		}
+
+		var err error
+		report, err = runReconciliation()
+		if errors.Is(err, errReconcileRunning) {
+			errorResponse(w, r, http.StatusConflict, "Reconciliation already running")
+			return
+		}
+		if err != nil {
+			log.Printf("Error running reconciliation: %s\n", err)
+			errorResponse(w, r, http.StatusInternalServerError, "Error running reconciliation")
+			return
+		}
+	default:
+		errorResponse(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		errorResponse(w, r, http.StatusInternalServerError, "Error converting report to json")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"report\":%s}\n", string(jsonData))
+}
+
+func getPlanHandler(w http.ResponseWriter, r *http.Request) {
+	// Authorize the request
+	if err := middleware(w, r); err != nil {
+		log.Printf("Middleware error: %s\n", err)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		errorResponse(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	plan, err := computePlan()
+	if err != nil {
+		log.Printf("Error computing plan: %s\n", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Error computing plan")
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		errorResponse(w, r, http.StatusInternalServerError, "Error converting plan to json")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"plan\":%s}\n", string(jsonData))
+}
+
+func getDriftHandler(w http.ResponseWriter, r *http.Request) {
+	// Authorize the request
+	if err := middleware(w, r); err != nil {
+		log.Printf("Middleware error: %s\n", err)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		errorResponse(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	drifts, err := detectDrift()
+	if err != nil {
+		log.Printf("Error detecting drift: %s\n", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Error detecting drift")
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(drifts, "", "  ")
+	if err != nil {
+		errorResponse(w, r, http.StatusInternalServerError, "Error converting drift to json")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"drift\":%s}\n", string(jsonData))
+}
+
+func challengeOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	// Authorize the request
+	if err := middleware(w, r); err != nil {
+		log.Printf("Middleware error: %s\n", err)
+		return
+	}
+
+	slug := r.PathValue("slug")
+	if slug == "" {
+		errorResponse(w, r, http.StatusBadRequest, "Missing challenge slug")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		overrides, err := getChallengeOverrides(slug)
+		if err != nil {
+			errorResponse(w, r, http.StatusInternalServerError, "Error getting overrides")
+			return
+		}
+
+		jsonData, err := json.MarshalIndent(overrides, "", "  ")
+		if err != nil {
+			errorResponse(w, r, http.StatusInternalServerError, "Error converting overrides to json")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"overrides\":%s}\n", string(jsonData))
+	case http.MethodDelete:
+		// Only the leader may change the state
+		if err := leaderMiddleware(w, r); err != nil {
+			log.Printf("Leader middleware error: %s\n", err)
+			return
+		}
+
+		if err := clearChallengeOverrides(slug); err != nil {
+			log.Printf("Error clearing overrides for challenge %s: %s\n", slug, err)
+			errorResponse(w, r, http.StatusInternalServerError, "Error clearing overrides")
+			return
+		}
+
+		log.Printf("Cleared overrides for challenge %s\n", slug)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"status\":\"ok\"}\n")
+	default:
+		errorResponse(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// ---------
+// Sync status
+// ---------
+
+func getSyncStatusHandler(w http.ResponseWriter, r *http.Request) {
+	// Authorize the request
+	if err := middleware(w, r); err != nil {
+		log.Printf("Middleware error: %s\n", err)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(getSyncStatuses(), "", "  ")
+	if err != nil {
+		errorResponse(w, r, http.StatusInternalServerError, "Error converting sync status to json")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"sync\":%s}\n", string(jsonData))
+}